@@ -0,0 +1,90 @@
+package database
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// loadEnv fills the fields of the struct pointed by v from the environment.
+// The variable name is prefix followed by the `env` tag of the field, a missing or empty
+// variable leaves the field as is when it is set, otherwise the `default` tag is used.
+// Supported field types are string, bool, ints, uints, time.Duration and comma separated []string.
+func loadEnv(prefix string, v interface{}) error {
+	rv := reflect.ValueOf(v).Elem()
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		f := rt.Field(i)
+		name, ok := f.Tag.Lookup("env")
+		if !ok {
+			continue
+		}
+		value := os.Getenv(prefix + name)
+		if value == "" {
+			if !rv.Field(i).IsZero() {
+				continue
+			}
+			value = f.Tag.Get("default")
+		}
+		if value == "" {
+			continue
+		}
+		if err := setField(rv.Field(i), value); err != nil {
+			return fmt.Errorf("invalid value of %s%s: %w", prefix, name, err)
+		}
+	}
+	return nil
+}
+
+// setField parses value into field according to its type.
+func setField(field reflect.Value, value string) error {
+	if field.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(d))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(n)
+	case reflect.Slice:
+		if field.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported type %v", field.Type())
+		}
+		parts := strings.Split(value, ",")
+		values := make([]string, 0, len(parts))
+		for _, p := range parts {
+			if p = strings.TrimSpace(p); p != "" {
+				values = append(values, p)
+			}
+		}
+		field.Set(reflect.ValueOf(values))
+	default:
+		return fmt.Errorf("unsupported type %v", field.Type())
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Mongo server error codes
+const (
+	codeHostUnreachable                 = 6
+	codeHostNotFound                    = 7
+	codeNetworkTimeout                  = 89
+	codeShutdownInProgress              = 91
+	codeWriteConflict                   = 112
+	codeDocumentValidationFailure       = 121
+	codePrimarySteppedDown              = 189
+	codeExceededTimeLimit               = 262
+	codeMaxTimeMSExpired                = 50
+	codeSocketException                 = 9001
+	codeNotWritablePrimary              = 10107
+	codeDuplicateKey                    = 11000
+	codeDuplicateKeyOnUpdate            = 11001
+	codeInterruptedAtShutdown           = 11600
+	codeInterruptedDueToReplStateChange = 11602
+	codeDuplicateKeyOnUpsert            = 12582
+	codeNotPrimaryNoSecondaryOk         = 13435
+	codeNotPrimaryOrSecondary           = 13436
+
+	retryableWriteErrorLabel = "RetryableWriteError"
+)
+
+// retryableCodes are the codes of errors after which the operation can be retried as is.
+var retryableCodes = []int{
+	codeHostUnreachable, codeHostNotFound, codeNetworkTimeout, codeShutdownInProgress, codeWriteConflict,
+	codePrimarySteppedDown, codeExceededTimeLimit, codeSocketException, codeNotWritablePrimary,
+	codeInterruptedAtShutdown, codeInterruptedDueToReplStateChange, codeNotPrimaryNoSecondaryOk, codeNotPrimaryOrSecondary,
+}
+
+// hasErrorCode reports whether err is a server error with one of codes,
+// write errors of WriteException and BulkWriteException included.
+func hasErrorCode(err error, codes ...int) bool {
+	var serverErr mongo.ServerError
+	if !errors.As(err, &serverErr) {
+		return false
+	}
+	for _, c := range codes {
+		if serverErr.HasErrorCode(c) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsDuplicate reports whether err is a duplicate key error of a write, bulk write or command.
+func IsDuplicate(err error) bool {
+	return err != nil && (hasErrorCode(err, codeDuplicateKey, codeDuplicateKeyOnUpdate, codeDuplicateKeyOnUpsert) || mongo.IsDuplicateKeyError(err))
+}
+
+// IsNotFound reports whether err is, or wraps, mongo.ErrNoDocuments.
+func IsNotFound(err error) bool {
+	return errors.Is(err, mongo.ErrNoDocuments)
+}
+
+// IsTimeout reports whether err is a client or server side timeout, context deadlines included.
+func IsTimeout(err error) bool {
+	return err != nil && (mongo.IsTimeout(err) || errors.Is(err, context.DeadlineExceeded) || hasErrorCode(err, codeMaxTimeMSExpired, codeNetworkTimeout))
+}
+
+// IsNetwork reports whether err is a network error.
+func IsNetwork(err error) bool {
+	return err != nil && (mongo.IsNetworkError(err) || hasErrorCode(err, codeHostUnreachable, codeHostNotFound, codeSocketException))
+}
+
+// IsWriteConflict reports whether err is a write conflict of concurrent transactions or updates.
+func IsWriteConflict(err error) bool {
+	return hasErrorCode(err, codeWriteConflict)
+}
+
+// IsDocumentValidation reports whether err is a rejection by the JSON schema validator of the collection.
+func IsDocumentValidation(err error) bool {
+	return hasErrorCode(err, codeDocumentValidationFailure)
+}
+
+// IsRetryable reports whether the operation which failed with err can be retried as is: network errors,
+// primary changes, write conflicts and errors labelled RetryableWriteError or TransientTransactionError.
+// Client side timeouts are not retryable as the deadline has passed.
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return false
+	}
+	return hasErrorLabel(err, retryableWriteErrorLabel) ||
+		hasErrorLabel(err, TransientTransactionErrorLabel) ||
+		mongo.IsNetworkError(err) ||
+		hasErrorCode(err, retryableCodes...)
+}
+
+// DuplicateKey describes the unique index a write collided with
+type DuplicateKey struct {
+	// Index is the name of the unique index e.g. email_1
+	Index string `json:"index"`
+	// Key holds the colliding values by field, parsed values of the error message are strings
+	Key bson.M `json:"key,omitempty"`
+}
+
+// duplicateKeyMessage matches the index and key of "E11000 duplicate key error collection: db.users index: email_1 dup key: { email: "a@b.c" }"
+var (
+	duplicateKeyMessage = regexp.MustCompile(`index: (\S+)(?: dup key: (\{.*\}))?`)
+	duplicateKeyField   = regexp.MustCompile(`([\w.$]+): ("(?:[^"\\]|\\.)*"|[^,}]+)`)
+)
+
+// GetDuplicateKey returns the index and key of the first duplicate key error of err,
+// read from the keyValue of the server response or parsed from the error message.
+//
+//	if dup, ok := database.GetDuplicateKey(err); ok {
+//		return c.Status(fiber.StatusConflict).JSON(dup)
+//	}
+func GetDuplicateKey(err error) (*DuplicateKey, bool) {
+	if !IsDuplicate(err) {
+		return nil, false
+	}
+	message, raw := duplicateError(err)
+
+	dup := &DuplicateKey{}
+	if m := duplicateKeyMessage.FindStringSubmatch(message); m != nil {
+		dup.Index = m[1]
+		if m[2] != "" {
+			dup.Key = parseDuplicateKey(m[2])
+		}
+	}
+	if raw != nil {
+		if v, err := raw.LookupErr("keyValue"); err == nil {
+			var key bson.M
+			if v.Unmarshal(&key) == nil {
+				dup.Key = key
+			}
+		}
+	}
+	return dup, true
+}
+
+// duplicateError returns the message and the raw server document of the first duplicate key error of err.
+func duplicateError(err error) (string, bson.Raw) {
+	isDup := func(code int) bool {
+		return code == codeDuplicateKey || code == codeDuplicateKeyOnUpdate || code == codeDuplicateKeyOnUpsert
+	}
+	var writeErr mongo.WriteException
+	if errors.As(err, &writeErr) {
+		for _, we := range writeErr.WriteErrors {
+			if isDup(we.Code) {
+				return we.Message, we.Raw
+			}
+		}
+	}
+	var bulkErr mongo.BulkWriteException
+	if errors.As(err, &bulkErr) {
+		for _, we := range bulkErr.WriteErrors {
+			if isDup(we.Code) {
+				return we.Message, we.Raw
+			}
+		}
+	}
+	var cmdErr mongo.CommandError
+	if errors.As(err, &cmdErr) {
+		return cmdErr.Message, cmdErr.Raw
+	}
+	return err.Error(), nil
+}
+
+// parseDuplicateKey parses the dup key document of the error message, values are kept as strings.
+func parseDuplicateKey(s string) bson.M {
+	key := bson.M{}
+	for _, m := range duplicateKeyField.FindAllStringSubmatch(s, -1) {
+		value := strings.TrimSpace(m[2])
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		}
+		key[m[1]] = value
+	}
+	return key
+}
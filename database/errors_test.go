@@ -0,0 +1,59 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const duplicateMessage = `E11000 duplicate key error collection: shop.users index: tenant_id_1_email_1 dup key: { tenant_id: "t1", email: "a@b.c" }`
+
+func TestIsDuplicate(t *testing.T) {
+	writeErr := mongo.WriteException{WriteErrors: mongo.WriteErrors{{Code: 11000, Message: duplicateMessage}}}
+	bulkErr := mongo.BulkWriteException{WriteErrors: []mongo.BulkWriteError{{WriteError: mongo.WriteError{Code: 11000, Message: duplicateMessage}}}}
+	cmdErr := mongo.CommandError{Code: 11000, Message: duplicateMessage}
+
+	for _, err := range []error{writeErr, bulkErr, cmdErr, fmt.Errorf("insert user: %w", writeErr)} {
+		assert.True(t, IsDuplicate(err))
+	}
+	assert.False(t, IsDuplicate(nil))
+	assert.False(t, IsDuplicate(mongo.CommandError{Code: 112}))
+}
+
+func TestGetDuplicateKey(t *testing.T) {
+	dup, ok := GetDuplicateKey(mongo.WriteException{WriteErrors: mongo.WriteErrors{{Code: 11000, Message: duplicateMessage}}})
+	assert.True(t, ok)
+	assert.Equal(t, &DuplicateKey{Index: "tenant_id_1_email_1", Key: bson.M{"tenant_id": "t1", "email": "a@b.c"}}, dup)
+
+	raw, err := bson.Marshal(bson.D{{Key: "code", Value: 11000}, {Key: "keyValue", Value: bson.D{{Key: "seq", Value: int32(7)}}}})
+	assert.NoError(t, err)
+	dup, ok = GetDuplicateKey(mongo.BulkWriteException{WriteErrors: []mongo.BulkWriteError{
+		{WriteError: mongo.WriteError{Code: 121, Message: "validation"}},
+		{WriteError: mongo.WriteError{Code: 11000, Message: "E11000 duplicate key error collection: shop.counters index: seq_1", Raw: raw}},
+	}})
+	assert.True(t, ok)
+	assert.Equal(t, &DuplicateKey{Index: "seq_1", Key: bson.M{"seq": int32(7)}}, dup)
+
+	_, ok = GetDuplicateKey(mongo.ErrNoDocuments)
+	assert.False(t, ok)
+}
+
+func TestErrorClassification(t *testing.T) {
+	assert.True(t, IsNotFound(fmt.Errorf("find user: %w", mongo.ErrNoDocuments)))
+	assert.True(t, IsTimeout(context.DeadlineExceeded))
+	assert.True(t, IsTimeout(mongo.CommandError{Code: 50, Name: "MaxTimeMSExpired"}))
+	assert.True(t, IsNetwork(mongo.CommandError{Labels: []string{"NetworkError"}}))
+	assert.True(t, IsWriteConflict(mongo.WriteException{WriteErrors: mongo.WriteErrors{{Code: 112}}}))
+	assert.True(t, IsDocumentValidation(mongo.WriteException{WriteErrors: mongo.WriteErrors{{Code: 121}}}))
+
+	assert.True(t, IsRetryable(mongo.CommandError{Code: 189, Name: "PrimarySteppedDown"}))
+	assert.True(t, IsRetryable(mongo.CommandError{Labels: []string{"RetryableWriteError"}}))
+	assert.True(t, IsRetryable(mongo.CommandError{Code: 112, Labels: []string{TransientTransactionErrorLabel}}))
+	assert.False(t, IsRetryable(context.DeadlineExceeded))
+	assert.False(t, IsRetryable(mongo.CommandError{Code: 11000}))
+	assert.False(t, IsRetryable(nil))
+}
@@ -0,0 +1,150 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/a01k-io/modules/logger"
+	"github.com/a01k-io/modules/paginator"
+	gormmysql "gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// DefaultSlowThreshold is the duration above which GormLogger reports queries as slow.
+const DefaultSlowThreshold = 200 * time.Millisecond
+
+// GormConfig defines the settings of OpenGorm
+type GormConfig struct {
+	MariaDB MariaDBConfig
+	// Logger receives the SQL logs, nil discards them
+	Logger *logger.Wrapper
+	// LogLevel defaults to gormlogger.Warn which logs errors and slow queries
+	LogLevel gormlogger.LogLevel
+	// SlowThreshold defaults to DefaultSlowThreshold, a negative value disables slow query logs
+	SlowThreshold time.Duration
+	// IgnoreRecordNotFoundError does not log gorm.ErrRecordNotFound
+	IgnoreRecordNotFoundError bool
+}
+
+// OpenGorm connects the pool of cfg.MariaDB, see ConnectMariaDBConfig, and wraps it in *gorm.DB logging through cfg.Logger.
+// Closing the *sql.DB returned by DB() closes the pool.
+func OpenGorm(ctx context.Context, cfg GormConfig) (*gorm.DB, error) {
+	sqlDB, err := ConnectMariaDBConfig(ctx, cfg.MariaDB)
+	if err != nil {
+		return nil, err
+	}
+	db, err := gorm.Open(gormmysql.New(gormmysql.Config{Conn: sqlDB}), &gorm.Config{
+		Logger: NewGormLogger(cfg.Logger, cfg.LogLevel, cfg.SlowThreshold, cfg.IgnoreRecordNotFoundError),
+	})
+	if err != nil {
+		_ = sqlDB.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// Paginate returns a scope applying the ORDER BY, LIMIT and OFFSET of paginator.BuildOffsetPaginationQuery,
+// a rejected sort_by is added to the errors of db so the query is not run unordered.
+//
+//	db.Model(&Order{}).Scopes(database.Paginate(*params)).Find(&orders)
+func Paginate(params paginator.PaginationQueryParam) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		clauses, err := paginator.BuildOffsetPaginationQuery(params)
+		if err != nil {
+			_ = db.AddError(err)
+			return db
+		}
+		return db.Clauses(clauses...)
+	}
+}
+
+// GormLogger routes the logs of gorm through logger.Wrapper
+type GormLogger struct {
+	lg                        *logger.Wrapper
+	level                     gormlogger.LogLevel
+	slowThreshold             time.Duration
+	ignoreRecordNotFoundError bool
+}
+
+// NewGormLogger returns GormLogger logging at level through lg, a nil lg discards the logs.
+// Zero level and slowThreshold default to gormlogger.Warn and DefaultSlowThreshold.
+func NewGormLogger(lg *logger.Wrapper, level gormlogger.LogLevel, slowThreshold time.Duration, ignoreRecordNotFoundError bool) *GormLogger {
+	if lg == nil {
+		level = gormlogger.Silent
+	}
+	if level == 0 {
+		level = gormlogger.Warn
+	}
+	if slowThreshold == 0 {
+		slowThreshold = DefaultSlowThreshold
+	}
+	return &GormLogger{lg: lg, level: level, slowThreshold: slowThreshold, ignoreRecordNotFoundError: ignoreRecordNotFoundError}
+}
+
+// LogMode returns a copy of the logger logging at level, it stays silent without logger.Wrapper.
+func (l *GormLogger) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
+	newLogger := *l
+	newLogger.level = level
+	if l.lg == nil {
+		newLogger.level = gormlogger.Silent
+	}
+	return &newLogger
+}
+
+// enabled reports whether messages of level are logged.
+func (l *GormLogger) enabled(level gormlogger.LogLevel) bool {
+	return l.lg != nil && l.level >= level
+}
+
+func (l *GormLogger) Info(ctx context.Context, msg string, args ...interface{}) {
+	if l.enabled(gormlogger.Info) {
+		l.lg.InfofCtx(ctx, msg, args...)
+	}
+}
+
+func (l *GormLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	if l.enabled(gormlogger.Warn) {
+		l.lg.WarnfCtx(ctx, msg, args...)
+	}
+}
+
+func (l *GormLogger) Error(ctx context.Context, msg string, args ...interface{}) {
+	if l.enabled(gormlogger.Error) {
+		l.lg.ErrorfCtx(ctx, msg, args...)
+	}
+}
+
+// Trace logs the failed queries at Error, the queries slower than the slow threshold at Warn
+// and every query at Info level.
+func (l *GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	if !l.enabled(gormlogger.Error) {
+		return
+	}
+	elapsed := time.Since(begin)
+	switch {
+	case err != nil && !(l.ignoreRecordNotFoundError && errors.Is(err, gorm.ErrRecordNotFound)):
+		sql, rows := fc()
+		l.lg.ErrorfCtx(ctx, "%s [%.3fms] [rows:%s] %s", err, msOf(elapsed), rowsOf(rows), sql)
+	case l.slowThreshold > 0 && elapsed > l.slowThreshold && l.enabled(gormlogger.Warn):
+		sql, rows := fc()
+		l.lg.WarnfCtx(ctx, "SLOW SQL >= %v [%.3fms] [rows:%s] %s", l.slowThreshold, msOf(elapsed), rowsOf(rows), sql)
+	case l.enabled(gormlogger.Info):
+		sql, rows := fc()
+		l.lg.InfofCtx(ctx, "[%.3fms] [rows:%s] %s", msOf(elapsed), rowsOf(rows), sql)
+	}
+}
+
+func msOf(d time.Duration) float64 {
+	return float64(d.Nanoseconds()) / 1e6
+}
+
+// rowsOf formats rows affected, gorm reports -1 when unknown.
+func rowsOf(rows int64) string {
+	if rows == -1 {
+		return "-"
+	}
+	return fmt.Sprint(rows)
+}
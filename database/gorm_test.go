@@ -0,0 +1,69 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/a01k-io/modules/logger"
+	"github.com/a01k-io/modules/paginator"
+	"github.com/stretchr/testify/assert"
+	gormmysql "gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+type order struct {
+	ID     int64
+	Amount int64
+}
+
+func TestPaginateScope(t *testing.T) {
+	db, err := gorm.Open(gormmysql.New(gormmysql.Config{DSN: GetMariaDBConnectionString("app", "secret", "localhost", "orders", 3306), SkipInitializeWithVersion: true}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+		Logger:               NewGormLogger(nil, 0, 0, false),
+	})
+	assert.NoError(t, err)
+
+	params := paginator.PaginationQueryParam{PageNo: 3, PageSize: 10, SortBy: []string{"amount:desc"}}
+	sql := db.ToSQL(func(tx *gorm.DB) *gorm.DB {
+		var orders []order
+		return tx.Scopes(Paginate(params)).Find(&orders)
+	})
+	assert.Equal(t, "SELECT * FROM `orders` ORDER BY `amount` DESC LIMIT 10 OFFSET 20", sql)
+}
+
+func TestPaginateScopeRejectedSort(t *testing.T) {
+	db, err := gorm.Open(gormmysql.New(gormmysql.Config{DSN: GetMariaDBConnectionString("app", "secret", "localhost", "orders", 3306), SkipInitializeWithVersion: true}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+		Logger:               NewGormLogger(nil, 0, 0, false),
+	})
+	assert.NoError(t, err)
+
+	params := paginator.PaginationQueryParam{PageNo: 1, PageSize: 10, SortBy: []string{"password:asc"}, SortSpec: paginator.NewSortSpec().Allow("amount", "amount")}
+	var orders []order
+	err = db.Scopes(Paginate(params)).Find(&orders).Error
+	var keyErr *paginator.SortKeyError
+	assert.True(t, errors.As(err, &keyErr))
+}
+
+func TestNewGormLoggerDefaults(t *testing.T) {
+	l := NewGormLogger(nil, gormlogger.Info, 0, true)
+	assert.Equal(t, gormlogger.Silent, l.level)
+	assert.Equal(t, DefaultSlowThreshold, l.slowThreshold)
+
+	debug := l.LogMode(gormlogger.Info).(*GormLogger)
+	assert.Equal(t, gormlogger.Silent, debug.level, "stays silent without logger")
+	assert.NotPanics(t, func() {
+		debug.Info(context.Background(), "query")
+		debug.Trace(context.Background(), time.Now(), func() (string, int64) { return "SELECT 1", 1 }, errors.New("failed"))
+	})
+
+	l = NewGormLogger(logger.New("test"), gormlogger.Warn, time.Second, true)
+	info := l.LogMode(gormlogger.Info).(*GormLogger)
+	assert.Equal(t, gormlogger.Info, info.level)
+	assert.Equal(t, gormlogger.Warn, l.level)
+}
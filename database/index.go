@@ -0,0 +1,388 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// idIndexName is the name of the default _id index, it is never managed.
+const idIndexName = "_id_"
+
+// DefaultIndexRegistry is used by RegisterIndexes and EnsureIndexes.
+var DefaultIndexRegistry = NewIndexRegistry()
+
+// IndexKey is a field of an index and its order
+type IndexKey struct {
+	Field string
+	Type  IndexType
+}
+
+// IndexSpec declares an index of a collection
+//
+//	database.RegisterIndexes(database.IndexSpec{
+//		Collection: "orders",
+//		Keys:       []database.IndexKey{{Field: "tenant_id", Type: database.ASC}, {Field: "created_at", Type: database.DESC}},
+//	})
+type IndexSpec struct {
+	Collection string
+	// Name defaults to the name generated by mongo e.g. tenant_id_1_created_at_-1
+	Name   string
+	Keys   []IndexKey
+	Unique bool
+	// PartialFilter indexes only the documents matching the filter
+	PartialFilter interface{}
+	// TTL removes the documents TTL after the time in the indexed date field, single field indexes only.
+	// It is rounded up to whole seconds, so a TTL under a second is never sent as expire-immediately.
+	TTL       time.Duration
+	Collation *options.Collation
+}
+
+// IndexName returns Name or the name generated by mongo.
+func (s IndexSpec) IndexName() string {
+	if s.Name != "" {
+		return s.Name
+	}
+	parts := make([]string, 0, len(s.Keys))
+	for _, k := range s.Keys {
+		parts = append(parts, fmt.Sprintf("%s_%d", k.Field, k.Type))
+	}
+	return strings.Join(parts, "_")
+}
+
+// Model returns the index model of the spec.
+func (s IndexSpec) Model() mongo.IndexModel {
+	keys := make(bson.D, 0, len(s.Keys))
+	for _, k := range s.Keys {
+		keys = append(keys, bson.E{Key: k.Field, Value: int32(k.Type)})
+	}
+	opts := options.Index().SetName(s.IndexName())
+	if s.Unique {
+		opts.SetUnique(true)
+	}
+	if s.PartialFilter != nil {
+		opts.SetPartialFilterExpression(s.PartialFilter)
+	}
+	if s.TTL > 0 {
+		opts.SetExpireAfterSeconds(s.expireAfterSeconds())
+	}
+	if s.Collation != nil {
+		opts.SetCollation(s.Collation)
+	}
+	return mongo.IndexModel{Keys: keys, Options: opts}
+}
+
+// expireAfterSeconds returns TTL rounded up to whole seconds.
+func (s IndexSpec) expireAfterSeconds() int32 {
+	return int32((s.TTL + time.Second - 1) / time.Second)
+}
+
+// IndexRegistry holds the declared indexes of the collections
+type IndexRegistry struct {
+	mu    sync.Mutex
+	specs []IndexSpec
+}
+
+// NewIndexRegistry returns an empty IndexRegistry.
+func NewIndexRegistry() *IndexRegistry {
+	return &IndexRegistry{}
+}
+
+// Register declares specs, it is safe to call from init functions of several packages.
+func (r *IndexRegistry) Register(specs ...IndexSpec) *IndexRegistry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.specs = append(r.specs, specs...)
+	return r
+}
+
+// Specs returns the declared specs.
+func (r *IndexRegistry) Specs() []IndexSpec {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]IndexSpec(nil), r.specs...)
+}
+
+// RegisterIndexes declares specs in DefaultIndexRegistry.
+func RegisterIndexes(specs ...IndexSpec) {
+	DefaultIndexRegistry.Register(specs...)
+}
+
+// IndexRef names an index of a collection
+type IndexRef struct {
+	Collection string `json:"collection"`
+	Name       string `json:"name"`
+}
+
+func (r IndexRef) String() string {
+	return r.Collection + "." + r.Name
+}
+
+// IndexReport is the outcome of EnsureIndexes
+type IndexReport struct {
+	// Created are the indexes created, with WithDryRun they are reported in Planned instead
+	Created   []IndexRef `json:"created"`
+	Unchanged []IndexRef `json:"unchanged"`
+	// Conflicts are indexes which exist with the declared name but another definition, they are left as is
+	// and have to be dropped, e.g. by a migration, to be recreated.
+	Conflicts []IndexRef `json:"conflicts"`
+	// Unmanaged are indexes which exist but are not declared
+	Unmanaged []IndexRef `json:"unmanaged"`
+	// Dropped are the unmanaged indexes dropped with WithDropUnmanaged
+	Dropped []IndexRef `json:"dropped"`
+	// Planned are the indexes WithDryRun would have created
+	Planned []IndexRef `json:"planned,omitempty"`
+	// PlannedDrops are the unmanaged indexes WithDryRun would have dropped
+	PlannedDrops []IndexRef `json:"planned_drops,omitempty"`
+}
+
+// EnsureOption configures EnsureIndexes
+type EnsureOption func(*ensureSettings)
+
+type ensureSettings struct {
+	dropUnmanaged bool
+	dryRun        bool
+}
+
+// WithDropUnmanaged drops the indexes of the registered collections which are not declared.
+func WithDropUnmanaged(drop bool) EnsureOption {
+	return func(s *ensureSettings) {
+		s.dropUnmanaged = drop
+	}
+}
+
+// WithDryRun only reports, nothing is created or dropped. The indexes which would be created or dropped
+// are reported in IndexReport.Planned and IndexReport.PlannedDrops.
+func WithDryRun(dryRun bool) EnsureOption {
+	return func(s *ensureSettings) {
+		s.dryRun = dryRun
+	}
+}
+
+// EnsureIndexes ensures the indexes of DefaultIndexRegistry in db, see IndexRegistry.Ensure.
+func EnsureIndexes(ctx context.Context, db *mongo.Database, opts ...EnsureOption) (*IndexReport, error) {
+	return DefaultIndexRegistry.Ensure(ctx, db, opts...)
+}
+
+// Ensure diffs the declared indexes against the existing indexes of their collections in db
+// and creates the missing ones. It stops at the first failure and returns the report so far with the error.
+func (r *IndexRegistry) Ensure(ctx context.Context, db *mongo.Database, opts ...EnsureOption) (*IndexReport, error) {
+	var s ensureSettings
+	for _, opt := range opts {
+		opt(&s)
+	}
+
+	byCollection := make(map[string][]IndexSpec)
+	for _, spec := range r.Specs() {
+		byCollection[spec.Collection] = append(byCollection[spec.Collection], spec)
+	}
+	collections := make([]string, 0, len(byCollection))
+	for c := range byCollection {
+		collections = append(collections, c)
+	}
+	sort.Strings(collections)
+
+	report := &IndexReport{}
+	for _, c := range collections {
+		indexes := db.Collection(c).Indexes()
+		existing, err := listIndexes(ctx, indexes)
+		if err != nil {
+			return report, fmt.Errorf("list indexes of %s: %w", c, err)
+		}
+
+		diff := diffIndexes(byCollection[c], existing)
+		report.Unchanged = append(report.Unchanged, refs(c, diff.unchanged)...)
+		report.Conflicts = append(report.Conflicts, refs(c, diff.conflicts)...)
+		report.Unmanaged = append(report.Unmanaged, refs(c, diff.unmanaged)...)
+
+		for _, spec := range diff.missing {
+			ref := IndexRef{Collection: c, Name: spec.IndexName()}
+			if s.dryRun {
+				report.Planned = append(report.Planned, ref)
+				continue
+			}
+			if _, err := indexes.CreateOne(ctx, spec.Model()); err != nil {
+				return report, fmt.Errorf("create index %s: %w", ref, err)
+			}
+			report.Created = append(report.Created, ref)
+		}
+		if !s.dropUnmanaged {
+			continue
+		}
+		for _, name := range diff.unmanaged {
+			ref := IndexRef{Collection: c, Name: name}
+			if s.dryRun {
+				report.PlannedDrops = append(report.PlannedDrops, ref)
+				continue
+			}
+			if _, err := indexes.DropOne(ctx, name); err != nil {
+				return report, fmt.Errorf("drop index %s: %w", ref, err)
+			}
+			report.Dropped = append(report.Dropped, ref)
+		}
+	}
+	return report, nil
+}
+
+// existingIndex is an index returned by listIndexes
+type existingIndex struct {
+	Name               string   `bson:"name"`
+	Key                bson.D   `bson:"key"`
+	Unique             bool     `bson:"unique"`
+	PartialFilter      bson.Raw `bson:"partialFilterExpression"`
+	ExpireAfterSeconds *int32   `bson:"expireAfterSeconds"`
+	Collation          *struct {
+		Locale   string `bson:"locale"`
+		Strength int    `bson:"strength"`
+	} `bson:"collation"`
+}
+
+func listIndexes(ctx context.Context, indexes mongo.IndexView) ([]existingIndex, error) {
+	cur, err := indexes.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	existing := make([]existingIndex, 0)
+	if err := cur.All(ctx, &existing); err != nil {
+		return nil, err
+	}
+	return existing, nil
+}
+
+type indexDiff struct {
+	missing   []IndexSpec
+	unchanged []string
+	conflicts []string
+	unmanaged []string
+}
+
+// diffIndexes compares the declared specs of a collection with its existing indexes by name.
+func diffIndexes(specs []IndexSpec, existing []existingIndex) indexDiff {
+	var diff indexDiff
+	byName := make(map[string]existingIndex, len(existing))
+	for _, e := range existing {
+		byName[e.Name] = e
+	}
+	declared := make(map[string]bool, len(specs))
+	for _, spec := range specs {
+		name := spec.IndexName()
+		declared[name] = true
+		e, ok := byName[name]
+		switch {
+		case !ok:
+			diff.missing = append(diff.missing, spec)
+		case spec.matches(e):
+			diff.unchanged = append(diff.unchanged, name)
+		default:
+			diff.conflicts = append(diff.conflicts, name)
+		}
+	}
+	for _, e := range existing {
+		if e.Name != idIndexName && !declared[e.Name] {
+			diff.unmanaged = append(diff.unmanaged, e.Name)
+		}
+	}
+	return diff
+}
+
+// matches reports whether the existing index has the definition of the spec.
+func (s IndexSpec) matches(e existingIndex) bool {
+	if len(e.Key) != len(s.Keys) || e.Unique != s.Unique {
+		return false
+	}
+	for i, k := range s.Keys {
+		if e.Key[i].Key != k.Field || fmt.Sprint(e.Key[i].Value) != fmt.Sprint(int32(k.Type)) {
+			return false
+		}
+	}
+
+	ttl := s.expireAfterSeconds()
+	if (e.ExpireAfterSeconds != nil) != (s.TTL > 0) || (e.ExpireAfterSeconds != nil && *e.ExpireAfterSeconds != ttl) {
+		return false
+	}
+
+	if (e.PartialFilter != nil) != (s.PartialFilter != nil) {
+		return false
+	}
+	if s.PartialFilter != nil && !sameDocument(s.PartialFilter, e.PartialFilter) {
+		return false
+	}
+
+	if (e.Collation != nil) != (s.Collation != nil) {
+		return false
+	}
+	if s.Collation != nil && (e.Collation.Locale != s.Collation.Locale || (s.Collation.Strength != 0 && e.Collation.Strength != s.Collation.Strength)) {
+		return false
+	}
+	return true
+}
+
+// sameDocument reports whether a and b are equal documents regardless of key order and number types.
+func sameDocument(a, b interface{}) bool {
+	na, err := normalizeDocument(a)
+	if err != nil {
+		return false
+	}
+	nb, err := normalizeDocument(b)
+	if err != nil {
+		return false
+	}
+	return reflect.DeepEqual(na, nb)
+}
+
+func normalizeDocument(v interface{}) (interface{}, error) {
+	raw, err := bson.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var m bson.M
+	if err := bson.Unmarshal(raw, &m); err != nil {
+		return nil, err
+	}
+	return normalizeValue(m), nil
+}
+
+// normalizeValue converts nested documents to maps and numbers to float64.
+func normalizeValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case bson.M:
+		m := make(map[string]interface{}, len(t))
+		for k, e := range t {
+			m[k] = normalizeValue(e)
+		}
+		return m
+	case bson.D:
+		m := make(map[string]interface{}, len(t))
+		for _, e := range t {
+			m[e.Key] = normalizeValue(e.Value)
+		}
+		return m
+	case bson.A:
+		a := make([]interface{}, 0, len(t))
+		for _, e := range t {
+			a = append(a, normalizeValue(e))
+		}
+		return a
+	case int32:
+		return float64(t)
+	case int64:
+		return float64(t)
+	}
+	return v
+}
+
+func refs(collection string, names []string) []IndexRef {
+	r := make([]IndexRef, 0, len(names))
+	for _, n := range names {
+		r = append(r, IndexRef{Collection: collection, Name: n})
+	}
+	return r
+}
@@ -0,0 +1,64 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestIndexSpecModel(t *testing.T) {
+	spec := IndexSpec{
+		Collection:    "sessions",
+		Keys:          []IndexKey{{Field: "tenant_id", Type: ASC}, {Field: "created_at", Type: DESC}},
+		Unique:        true,
+		PartialFilter: bson.M{"deleted_at": nil},
+	}
+	assert.Equal(t, "tenant_id_1_created_at_-1", spec.IndexName())
+
+	model := spec.Model()
+	assert.Equal(t, bson.D{{Key: "tenant_id", Value: int32(1)}, {Key: "created_at", Value: int32(-1)}}, model.Keys)
+	assert.Equal(t, "tenant_id_1_created_at_-1", *model.Options.Name)
+	assert.True(t, *model.Options.Unique)
+
+	ttl := IndexSpec{Name: "expiry", Keys: []IndexKey{{Field: "expires_at", Type: ASC}}, TTL: time.Hour}.Model()
+	assert.Equal(t, int32(3600), *ttl.Options.ExpireAfterSeconds)
+
+	subSecond := IndexSpec{Name: "expiry", Keys: []IndexKey{{Field: "expires_at", Type: ASC}}, TTL: 500 * time.Millisecond}
+	assert.Equal(t, int32(1), *subSecond.Model().Options.ExpireAfterSeconds, "a TTL under a second must not expire immediately")
+	oneSecond := int32(1)
+	assert.True(t, subSecond.matches(existingIndex{Name: "expiry", Key: bson.D{{Key: "expires_at", Value: int32(1)}}, ExpireAfterSeconds: &oneSecond}))
+	zero := int32(0)
+	assert.False(t, subSecond.matches(existingIndex{Name: "expiry", Key: bson.D{{Key: "expires_at", Value: int32(1)}}, ExpireAfterSeconds: &zero}))
+}
+
+func TestDiffIndexes(t *testing.T) {
+	filter, err := bson.Marshal(bson.D{{Key: "status", Value: bson.D{{Key: "$in", Value: bson.A{int32(1), int32(2)}}}}, {Key: "active", Value: true}})
+	assert.NoError(t, err)
+	ttl := int32(60)
+
+	specs := []IndexSpec{
+		{Keys: []IndexKey{{Field: "tenant_id", Type: ASC}}},
+		{Keys: []IndexKey{{Field: "code", Type: ASC}}, Unique: true, PartialFilter: bson.M{"active": true, "status": bson.M{"$in": bson.A{1, 2}}}},
+		{Keys: []IndexKey{{Field: "expires_at", Type: ASC}}, TTL: time.Minute},
+		{Keys: []IndexKey{{Field: "name", Type: ASC}}, Collation: &options.Collation{Locale: "en", Strength: 2}},
+		{Keys: []IndexKey{{Field: "created_at", Type: DESC}}},
+	}
+	existing := []existingIndex{
+		{Name: "_id_", Key: bson.D{{Key: "_id", Value: int32(1)}}},
+		{Name: "tenant_id_1", Key: bson.D{{Key: "tenant_id", Value: int32(1)}}},
+		{Name: "code_1", Key: bson.D{{Key: "code", Value: int32(1)}}, Unique: true, PartialFilter: filter},
+		{Name: "expires_at_1", Key: bson.D{{Key: "expires_at", Value: int32(1)}}, ExpireAfterSeconds: &ttl},
+		{Name: "name_1", Key: bson.D{{Key: "name", Value: float64(1)}}},
+		{Name: "legacy_1", Key: bson.D{{Key: "legacy", Value: int32(1)}}},
+	}
+
+	diff := diffIndexes(specs, existing)
+	assert.Equal(t, []string{"tenant_id_1", "code_1", "expires_at_1"}, diff.unchanged)
+	assert.Equal(t, []string{"name_1"}, diff.conflicts)
+	assert.Equal(t, []string{"legacy_1"}, diff.unmanaged)
+	assert.Len(t, diff.missing, 1)
+	assert.Equal(t, "created_at_-1", diff.missing[0].IndexName())
+}
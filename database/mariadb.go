@@ -1,21 +1,219 @@
 package database
 
 import (
+	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
 	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
 )
 
+// MariaDBConfig defines the settings of a MariaDB connection pool, the `env` tags are read by LoadMariaDBConfig
+//
+//	cfg, err := database.LoadMariaDBConfig("ORDERS_DB_")
+//	db, err := database.ConnectMariaDBConfig(ctx, cfg)
+type MariaDBConfig struct {
+	Host     string `env:"HOST" default:"localhost"`
+	Port     int    `env:"PORT" default:"3306"`
+	User     string `env:"USER"`
+	Password string `env:"PASSWORD"`
+	DBName   string `env:"DB_NAME"`
+	// Loc is the time zone of time.Time values e.g. UTC or Asia/Dhaka
+	Loc string `env:"LOC" default:"UTC"`
+	// TLS is empty to disable TLS, true, skip-verify or preferred
+	TLS string `env:"TLS"`
+	// TLSCAFile is the PEM file of the certificate authorities, it enables TLS verified against them,
+	// still optional with TLS preferred
+	TLSCAFile string `env:"TLS_CA_FILE"`
+	// Timeout is the dial timeout, ReadTimeout and WriteTimeout the I/O timeouts
+	Timeout      time.Duration `env:"TIMEOUT" default:"10s"`
+	ReadTimeout  time.Duration `env:"READ_TIMEOUT" default:"30s"`
+	WriteTimeout time.Duration `env:"WRITE_TIMEOUT" default:"30s"`
+
+	MaxOpenConns    int           `env:"MAX_OPEN_CONNS" default:"25"`
+	MaxIdleConns    int           `env:"MAX_IDLE_CONNS" default:"25"`
+	ConnMaxLifetime time.Duration `env:"CONN_MAX_LIFETIME" default:"5m"`
+	ConnMaxIdleTime time.Duration `env:"CONN_MAX_IDLE_TIME" default:"1m"`
+}
+
+// DefaultMariaDBConfig holds the defaults applied to the zero fields of MariaDBConfig.
+var DefaultMariaDBConfig = MariaDBConfig{
+	Host:            "localhost",
+	Port:            3306,
+	Loc:             "UTC",
+	Timeout:         10 * time.Second,
+	ReadTimeout:     30 * time.Second,
+	WriteTimeout:    30 * time.Second,
+	MaxOpenConns:    25,
+	MaxIdleConns:    25,
+	ConnMaxLifetime: 5 * time.Minute,
+	ConnMaxIdleTime: time.Minute,
+}
+
+// LoadMariaDBConfig returns MariaDBConfig read from the environment variables named prefix followed by the `env` tag,
+// e.g. DB_HOST for the prefix "DB_".
+func LoadMariaDBConfig(prefix string) (MariaDBConfig, error) {
+	var cfg MariaDBConfig
+	if err := loadEnv(prefix, &cfg); err != nil {
+		return MariaDBConfig{}, err
+	}
+	return cfg, nil
+}
+
+// withDefaults returns c with the zero fields taken from DefaultMariaDBConfig.
+func (c MariaDBConfig) withDefaults() MariaDBConfig {
+	d := DefaultMariaDBConfig
+	if c.Host == "" {
+		c.Host = d.Host
+	}
+	if c.Port == 0 {
+		c.Port = d.Port
+	}
+	if c.Loc == "" {
+		c.Loc = d.Loc
+	}
+	if c.Timeout == 0 {
+		c.Timeout = d.Timeout
+	}
+	if c.ReadTimeout == 0 {
+		c.ReadTimeout = d.ReadTimeout
+	}
+	if c.WriteTimeout == 0 {
+		c.WriteTimeout = d.WriteTimeout
+	}
+	if c.MaxOpenConns == 0 {
+		c.MaxOpenConns = d.MaxOpenConns
+	}
+	if c.MaxIdleConns == 0 {
+		c.MaxIdleConns = d.MaxIdleConns
+	}
+	if c.ConnMaxLifetime == 0 {
+		c.ConnMaxLifetime = d.ConnMaxLifetime
+	}
+	if c.ConnMaxIdleTime == 0 {
+		c.ConnMaxIdleTime = d.ConnMaxIdleTime
+	}
+	return c
+}
+
+// MySQLConfig returns the driver config of c with utf8mb4, parsed time values and the defaults applied.
+// With TLSCAFile the TLS config is registered in the driver under a name derived from the host, the CA file
+// and the TLS mode, so configs differing in any of them do not replace each other. The preferred mode keeps
+// falling back to plaintext when the server does not support TLS.
+func (c MariaDBConfig) MySQLConfig() (*mysql.Config, error) {
+	c = c.withDefaults()
+	loc, err := time.LoadLocation(c.Loc)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := mysql.NewConfig()
+	cfg.User = c.User
+	cfg.Passwd = c.Password
+	cfg.Net = "tcp"
+	cfg.Addr = net.JoinHostPort(c.Host, strconv.Itoa(c.Port))
+	cfg.DBName = c.DBName
+	cfg.Params = map[string]string{"charset": "utf8mb4"}
+	cfg.Collation = "utf8mb4_unicode_ci"
+	cfg.ParseTime = true
+	cfg.Loc = loc
+	cfg.Timeout = c.Timeout
+	cfg.ReadTimeout = c.ReadTimeout
+	cfg.WriteTimeout = c.WriteTimeout
+	cfg.TLSConfig = c.TLS
+
+	if c.TLSCAFile != "" {
+		tlsConfig, err := newTLSConfig(c.TLSCAFile, c.TLS == "skip-verify")
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.ServerName = c.Host
+		name := tlsConfigName(c.Host, c.TLSCAFile, c.TLS)
+		if err := mysql.RegisterTLSConfig(name, tlsConfig); err != nil {
+			return nil, err
+		}
+		cfg.TLSConfig = name
+		cfg.AllowFallbackToPlaintext = c.TLS == "preferred"
+	}
+	return cfg, nil
+}
+
+// tlsConfigName returns the driver registration name of the TLS config of host, caFile and mode.
+func tlsConfigName(host, caFile, mode string) string {
+	sum := sha256.Sum256([]byte(host + "\x00" + caFile + "\x00" + mode))
+	return "mariadb-" + hex.EncodeToString(sum[:8])
+}
+
+// DSN returns the data source name of c, credentials are escaped by the driver.
+func (c MariaDBConfig) DSN() (string, error) {
+	cfg, err := c.MySQLConfig()
+	if err != nil {
+		return "", err
+	}
+	return cfg.FormatDSN(), nil
+}
+
+// ConnectMariaDBConfig opens the connection pool of cfg, sets its sizes and lifetimes and pings it within ctx.
+func ConnectMariaDBConfig(ctx context.Context, cfg MariaDBConfig) (*sql.DB, error) {
+	mysqlConfig, err := cfg.MySQLConfig()
+	if err != nil {
+		return nil, err
+	}
+	connector, err := mysql.NewConnector(mysqlConfig)
+	if err != nil {
+		return nil, err
+	}
+	db := sql.OpenDB(connector)
+	configurePool(db, cfg.withDefaults())
+
+	if err := db.PingContext(ctx); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// configurePool applies the pool sizes and lifetimes of cfg to db.
+func configurePool(db *sql.DB, cfg MariaDBConfig) {
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+}
+
+// GetMariaDBConnectionString returns the data source name of the database, credentials are escaped by the driver.
 func GetMariaDBConnectionString(username, password, host, dbname string, port int) string {
-	return fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?parseTime=1&charset=utf8mb4&collation=utf8mb4_unicode_ci", username, password, host, port, dbname)
+	cfg := mysql.NewConfig()
+	cfg.User = username
+	cfg.Passwd = password
+	cfg.Net = "tcp"
+	cfg.Addr = net.JoinHostPort(host, strconv.Itoa(port))
+	cfg.DBName = dbname
+	cfg.Params = map[string]string{"charset": "utf8mb4"}
+	cfg.Collation = "utf8mb4_unicode_ci"
+	cfg.ParseTime = true
+	return cfg.FormatDSN()
 }
 
+// ConnectMariaDB opens the connection pool of the data source name uri with the pool settings
+// of DefaultMariaDBConfig and pings it.
 func ConnectMariaDB(uri string) (*sql.DB, error) {
 	db, err := sql.Open("mysql", uri)
 	if err != nil {
 		return nil, err
 	}
-	db.SetMaxOpenConns(100)
-	db.SetMaxIdleConns(100)
+	configurePool(db, DefaultMariaDBConfig)
 
+	ctx, cancelFunc := context.WithTimeout(context.Background(), ConnectionTimeoutInSecond*time.Second)
+	defer cancelFunc()
+	if err := db.PingContext(ctx); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("ping mariadb: %w", err)
+	}
 	return db, nil
 }
@@ -0,0 +1,89 @@
+package database
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetMariaDBConnectionStringEscapesCredentials(t *testing.T) {
+	dsn := GetMariaDBConnectionString("app", "p@ss:w/rd?", "db.local", "orders", 3307)
+
+	cfg, err := mysql.ParseDSN(dsn)
+	assert.NoError(t, err)
+	assert.Equal(t, "app", cfg.User)
+	assert.Equal(t, "p@ss:w/rd?", cfg.Passwd)
+	assert.Equal(t, "db.local:3307", cfg.Addr)
+	assert.Equal(t, "orders", cfg.DBName)
+	assert.True(t, cfg.ParseTime)
+	assert.Equal(t, "utf8mb4_unicode_ci", cfg.Collation)
+}
+
+func TestMariaDBConfigDSN(t *testing.T) {
+	dsn, err := MariaDBConfig{User: "app", Password: "s3cr@t", DBName: "orders", Loc: "Asia/Dhaka", TLS: "preferred", ReadTimeout: time.Minute}.DSN()
+	assert.NoError(t, err)
+
+	cfg, err := mysql.ParseDSN(dsn)
+	assert.NoError(t, err)
+	assert.Equal(t, "s3cr@t", cfg.Passwd)
+	assert.Equal(t, "localhost:3306", cfg.Addr)
+	assert.Equal(t, "Asia/Dhaka", cfg.Loc.String())
+	assert.Equal(t, "preferred", cfg.TLSConfig)
+	assert.Equal(t, 10*time.Second, cfg.Timeout)
+	assert.Equal(t, time.Minute, cfg.ReadTimeout)
+
+	_, err = MariaDBConfig{Loc: "Nowhere/City"}.DSN()
+	assert.Error(t, err)
+}
+
+// writeTestCA writes a self-signed CA certificate into a PEM file and returns its path.
+func writeTestCA(t *testing.T, name string) string {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	assert.NoError(t, err)
+	path := filepath.Join(t.TempDir(), name+".pem")
+	assert.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	return path
+}
+
+func TestMariaDBConfigTLSCAFile(t *testing.T) {
+	caA, caB := writeTestCA(t, "a"), writeTestCA(t, "b")
+
+	required, err := MariaDBConfig{Host: "db.local", TLS: "true", TLSCAFile: caA}.MySQLConfig()
+	assert.NoError(t, err)
+	assert.False(t, required.AllowFallbackToPlaintext)
+
+	otherCA, err := MariaDBConfig{Host: "db.local", TLS: "true", TLSCAFile: caB}.MySQLConfig()
+	assert.NoError(t, err)
+	assert.NotEqual(t, required.TLSConfig, otherCA.TLSConfig, "another CA file of the same host must not replace the config")
+
+	preferred, err := MariaDBConfig{Host: "db.local", TLS: "preferred", TLSCAFile: caA}.MySQLConfig()
+	assert.NoError(t, err)
+	assert.NotEqual(t, required.TLSConfig, preferred.TLSConfig)
+	assert.True(t, preferred.AllowFallbackToPlaintext, "preferred must not turn into required TLS")
+
+	cfg, err := mysql.ParseDSN(preferred.FormatDSN())
+	assert.NoError(t, err)
+	assert.True(t, cfg.AllowFallbackToPlaintext)
+	assert.Equal(t, "db.local", cfg.TLS.ServerName)
+}
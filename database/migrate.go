@@ -0,0 +1,373 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/a01k-io/modules/nanoid"
+)
+
+// DefaultMigrationsName is the collection or table recording the applied migrations,
+// the lock is kept in the one suffixed with "_lock".
+const DefaultMigrationsName = "_migrations"
+
+var (
+	// ErrMigrationLocked means another replica holds the migration lock.
+	ErrMigrationLocked = errors.New("migrations are locked by another process")
+	// ErrIrreversibleMigration means a migration to roll back has no Down func or is not registered.
+	ErrIrreversibleMigration = errors.New("migration can not be rolled back")
+	// ErrDuplicateMigration means two registered migrations have the same version.
+	ErrDuplicateMigration = errors.New("duplicate migration version")
+)
+
+// Migration is a versioned schema or data change. Up and Down capture the database they change,
+// they should be idempotent as a failure after Up and before its record leaves it unrecorded.
+type Migration struct {
+	Version     int64
+	Description string
+	Up          func(ctx context.Context) error
+	Down        func(ctx context.Context) error
+}
+
+// AppliedMigration is the record of an applied migration.
+type AppliedMigration struct {
+	Version     int64     `bson:"_id"`
+	Description string    `bson:"description"`
+	AppliedAt   time.Time `bson:"applied_at"`
+}
+
+// MigrationStore records the applied migrations and holds the migration lock of a database,
+// see NewMongoMigrationStore and NewSQLMigrationStore.
+type MigrationStore interface {
+	// Init creates the records and the lock storage when missing, it is not called by read only runs.
+	Init(ctx context.Context) error
+	// Lock acquires or extends the lock for owner until ttl, it reports false when another owner holds it.
+	Lock(ctx context.Context, owner string, ttl time.Duration) (bool, error)
+	Unlock(ctx context.Context, owner string) error
+	// Applied returns the applied migrations ordered by version, none when the storage does not exist yet.
+	Applied(ctx context.Context) ([]AppliedMigration, error)
+	Record(ctx context.Context, m AppliedMigration) error
+	Remove(ctx context.Context, version int64) error
+}
+
+// MigrationStatus is the state of a migration
+type MigrationStatus struct {
+	Version     int64     `json:"version"`
+	Description string    `json:"description"`
+	Applied     bool      `json:"applied"`
+	AppliedAt   time.Time `json:"applied_at"`
+	// Registered is false for applied versions without a registered migration
+	Registered bool `json:"registered"`
+}
+
+// MigrationReport lists the migrations run, or to be run in dry-run mode, in order.
+type MigrationReport struct {
+	DryRun     bool              `json:"dry_run"`
+	Migrations []MigrationStatus `json:"migrations"`
+}
+
+// MigratorOption configures Migrator
+type MigratorOption func(*Migrator)
+
+// WithMigrationDryRun reports the migrations to run without running them or taking the lock.
+func WithMigrationDryRun(dryRun bool) MigratorOption {
+	return func(m *Migrator) {
+		m.dryRun = dryRun
+	}
+}
+
+// WithLockTTL sets how long the lock is held without renewal, it is renewed every third of ttl while
+// the migrations run. Defaults to 10 minutes.
+func WithLockTTL(ttl time.Duration) MigratorOption {
+	return func(m *Migrator) {
+		m.lockTTL = ttl
+	}
+}
+
+// WithLockWait waits up to d for a lock held by another replica instead of returning ErrMigrationLocked.
+func WithLockWait(d time.Duration) MigratorOption {
+	return func(m *Migrator) {
+		m.lockWait = d
+	}
+}
+
+// WithLockOwner sets the lock owner, defaults to the host name, pid and a random id.
+func WithLockOwner(owner string) MigratorOption {
+	return func(m *Migrator) {
+		m.owner = owner
+	}
+}
+
+// Migrator runs the registered migrations against a MigrationStore
+//
+//	m := database.NewMongoMigrator(client.Database("orders"))
+//	m.Register(database.Migration{Version: 1, Description: "backfill tenant", Up: backfillTenant})
+//	report, err := m.Up(ctx)
+type Migrator struct {
+	store      MigrationStore
+	migrations []Migration
+	dryRun     bool
+	lockTTL    time.Duration
+	lockWait   time.Duration
+	owner      string
+}
+
+// NewMigrator returns Migrator recording the migrations in store.
+func NewMigrator(store MigrationStore, opts ...MigratorOption) *Migrator {
+	hostname, _ := os.Hostname()
+	m := &Migrator{
+		store:   store,
+		lockTTL: 10 * time.Minute,
+		owner:   fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), nanoid.NewID(8)),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// Register adds migrations, they run in version order whatever the order of registration.
+func (m *Migrator) Register(migrations ...Migration) *Migrator {
+	m.migrations = append(m.migrations, migrations...)
+	return m
+}
+
+// sorted returns the registered migrations ordered by version.
+func (m *Migrator) sorted() ([]Migration, error) {
+	migrations := append([]Migration(nil), m.migrations...)
+	sort.SliceStable(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].Version == migrations[i-1].Version {
+			return nil, fmt.Errorf("%w: %d", ErrDuplicateMigration, migrations[i].Version)
+		}
+	}
+	return migrations, nil
+}
+
+// Status returns the state of every registered or applied migration ordered by version, it only reads the store.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	migrations, err := m.sorted()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := m.store.Applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*MigrationStatus)
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	for _, mg := range migrations {
+		statuses = append(statuses, MigrationStatus{Version: mg.Version, Description: mg.Description, Registered: true})
+	}
+	for i := range statuses {
+		byVersion[statuses[i].Version] = &statuses[i]
+	}
+	for _, a := range applied {
+		if s, ok := byVersion[a.Version]; ok {
+			s.Applied, s.AppliedAt = true, a.AppliedAt
+			continue
+		}
+		statuses = append(statuses, MigrationStatus{Version: a.Version, Description: a.Description, Applied: true, AppliedAt: a.AppliedAt})
+	}
+	sort.SliceStable(statuses, func(i, j int) bool {
+		return statuses[i].Version < statuses[j].Version
+	})
+	return statuses, nil
+}
+
+// Up runs the pending migrations in version order holding the lock, it stops at the first failure.
+func (m *Migrator) Up(ctx context.Context) (*MigrationReport, error) {
+	return m.UpTo(ctx, 0)
+}
+
+// UpTo runs the pending migrations up to and including version, zero means all.
+func (m *Migrator) UpTo(ctx context.Context, version int64) (*MigrationReport, error) {
+	report := &MigrationReport{DryRun: m.dryRun, Migrations: make([]MigrationStatus, 0)}
+	err := m.locked(ctx, func() error {
+		migrations, err := m.sorted()
+		if err != nil {
+			return err
+		}
+		applied, err := m.appliedVersions(ctx)
+		if err != nil {
+			return err
+		}
+		for _, mg := range migrations {
+			if applied[mg.Version] || (version > 0 && mg.Version > version) {
+				continue
+			}
+			status := MigrationStatus{Version: mg.Version, Description: mg.Description, Registered: true}
+			if !m.dryRun {
+				err := m.heartbeat(ctx, func(ctx context.Context) error {
+					if mg.Up != nil {
+						if err := mg.Up(ctx); err != nil {
+							return fmt.Errorf("migration %d up: %w", mg.Version, err)
+						}
+					}
+					status.Applied, status.AppliedAt = true, time.Now().UTC()
+					return m.store.Record(ctx, AppliedMigration{Version: mg.Version, Description: mg.Description, AppliedAt: status.AppliedAt})
+				})
+				if err != nil {
+					return err
+				}
+			}
+			report.Migrations = append(report.Migrations, status)
+		}
+		return nil
+	})
+	return report, err
+}
+
+// Down rolls back the last steps applied migrations in reverse version order holding the lock.
+func (m *Migrator) Down(ctx context.Context, steps int) (*MigrationReport, error) {
+	report := &MigrationReport{DryRun: m.dryRun, Migrations: make([]MigrationStatus, 0)}
+	err := m.locked(ctx, func() error {
+		migrations, err := m.sorted()
+		if err != nil {
+			return err
+		}
+		byVersion := make(map[int64]Migration, len(migrations))
+		for _, mg := range migrations {
+			byVersion[mg.Version] = mg
+		}
+		applied, err := m.store.Applied(ctx)
+		if err != nil {
+			return err
+		}
+
+		for i := len(applied) - 1; i >= 0 && len(report.Migrations) < steps; i-- {
+			mg, ok := byVersion[applied[i].Version]
+			if !ok || mg.Down == nil {
+				return fmt.Errorf("%w: %d", ErrIrreversibleMigration, applied[i].Version)
+			}
+			status := MigrationStatus{Version: mg.Version, Description: mg.Description, Registered: true, Applied: true, AppliedAt: applied[i].AppliedAt}
+			if !m.dryRun {
+				err := m.heartbeat(ctx, func(ctx context.Context) error {
+					if err := mg.Down(ctx); err != nil {
+						return fmt.Errorf("migration %d down: %w", mg.Version, err)
+					}
+					return m.store.Remove(ctx, mg.Version)
+				})
+				if err != nil {
+					return err
+				}
+				status.Applied, status.AppliedAt = false, time.Time{}
+			}
+			report.Migrations = append(report.Migrations, status)
+		}
+		return nil
+	})
+	return report, err
+}
+
+func (m *Migrator) appliedVersions(ctx context.Context) (map[int64]bool, error) {
+	applied, err := m.store.Applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	versions := make(map[int64]bool, len(applied))
+	for _, a := range applied {
+		versions[a.Version] = true
+	}
+	return versions, nil
+}
+
+// locked initializes the store and runs fn holding the lock, in dry-run mode the store is only read.
+func (m *Migrator) locked(ctx context.Context, fn func() error) error {
+	if m.dryRun {
+		return fn()
+	}
+	if err := m.store.Init(ctx); err != nil {
+		return err
+	}
+	if err := m.acquire(ctx); err != nil {
+		return err
+	}
+	defer func() {
+		_ = m.store.Unlock(context.Background(), m.owner)
+	}()
+	return fn()
+}
+
+// acquire takes the lock, waiting up to lockWait while another owner holds it.
+func (m *Migrator) acquire(ctx context.Context) error {
+	deadline := time.Now().Add(m.lockWait)
+	for {
+		ok, err := m.store.Lock(ctx, m.owner, m.lockTTL)
+		if err != nil {
+			return err
+		}
+		if ok {
+			return nil
+		}
+		if !time.Now().Before(deadline) {
+			return ErrMigrationLocked
+		}
+		if err := sleep(ctx, time.Second); err != nil {
+			return err
+		}
+	}
+}
+
+// heartbeat renews the lock and runs fn, the lock is renewed every third of lockTTL until fn returns.
+// When a renewal fails the context of fn is canceled and the renewal error is returned.
+func (m *Migrator) heartbeat(ctx context.Context, fn func(ctx context.Context) error) error {
+	if err := m.renew(ctx); err != nil {
+		return err
+	}
+	interval := m.lockTTL / 3
+	if interval <= 0 {
+		return fn(ctx)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var renewErr error
+	done, stopped := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := m.renew(ctx); err != nil {
+					renewErr = err
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+
+	err := fn(ctx)
+	close(done)
+	<-stopped
+	if renewErr != nil {
+		return fmt.Errorf("renew migration lock: %w", renewErr)
+	}
+	return err
+}
+
+// renew extends the lock, it fails when the lock expired and was taken by another owner.
+func (m *Migrator) renew(ctx context.Context) error {
+	ok, err := m.store.Lock(ctx, m.owner, m.lockTTL)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrMigrationLocked
+	}
+	return nil
+}
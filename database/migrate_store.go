@@ -0,0 +1,187 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// migrationLockID is the id of the single lock document or row.
+const migrationLockID = 1
+
+// errNoSuchTable is the MariaDB error number of a query on a missing table.
+const errNoSuchTable = 1146
+
+// MongoMigrationStore records migrations in a collection of a mongo database
+type MongoMigrationStore struct {
+	records *mongo.Collection
+	lock    *mongo.Collection
+}
+
+// NewMongoMigrationStore returns MongoMigrationStore using the collection name, DefaultMigrationsName when empty.
+func NewMongoMigrationStore(db *mongo.Database, name string) *MongoMigrationStore {
+	if name == "" {
+		name = DefaultMigrationsName
+	}
+	return &MongoMigrationStore{records: db.Collection(name), lock: db.Collection(name + "_lock")}
+}
+
+// NewMongoMigrator returns Migrator recording the migrations in the DefaultMigrationsName collection of db.
+func NewMongoMigrator(db *mongo.Database, opts ...MigratorOption) *Migrator {
+	return NewMigrator(NewMongoMigrationStore(db, ""), opts...)
+}
+
+// Init is a no-op, collections are created on first write.
+func (s *MongoMigrationStore) Init(context.Context) error {
+	return nil
+}
+
+// Lock upserts the lock document unless it is held by another owner and not expired,
+// which fails with a duplicate key error.
+func (s *MongoMigrationStore) Lock(ctx context.Context, owner string, ttl time.Duration) (bool, error) {
+	now := time.Now().UTC()
+	filter := bson.M{"_id": migrationLockID, "$or": bson.A{
+		bson.M{"owner": owner},
+		bson.M{"expires_at": bson.M{"$lt": now}},
+	}}
+	update := bson.M{"$set": bson.M{"owner": owner, "expires_at": now.Add(ttl)}}
+	_, err := s.lock.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if IsDuplicate(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (s *MongoMigrationStore) Unlock(ctx context.Context, owner string) error {
+	_, err := s.lock.DeleteOne(ctx, bson.M{"_id": migrationLockID, "owner": owner})
+	return err
+}
+
+func (s *MongoMigrationStore) Applied(ctx context.Context) ([]AppliedMigration, error) {
+	cur, err := s.records.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	applied := make([]AppliedMigration, 0)
+	if err := cur.All(ctx, &applied); err != nil {
+		return nil, err
+	}
+	return applied, nil
+}
+
+func (s *MongoMigrationStore) Record(ctx context.Context, m AppliedMigration) error {
+	_, err := s.records.InsertOne(ctx, m)
+	return err
+}
+
+func (s *MongoMigrationStore) Remove(ctx context.Context, version int64) error {
+	_, err := s.records.DeleteOne(ctx, bson.M{"_id": version})
+	return err
+}
+
+// SQLMigrationStore records migrations in a MariaDB table
+type SQLMigrationStore struct {
+	db      *sql.DB
+	records string
+	lock    string
+}
+
+// NewSQLMigrationStore returns SQLMigrationStore using the table name, DefaultMigrationsName when empty.
+func NewSQLMigrationStore(db *sql.DB, name string) *SQLMigrationStore {
+	if name == "" {
+		name = DefaultMigrationsName
+	}
+	return &SQLMigrationStore{db: db, records: quoteIdentifier(name), lock: quoteIdentifier(name + "_lock")}
+}
+
+// NewSQLMigrator returns Migrator recording the migrations in the DefaultMigrationsName table of db.
+func NewSQLMigrator(db *sql.DB, opts ...MigratorOption) *Migrator {
+	return NewMigrator(NewSQLMigrationStore(db, ""), opts...)
+}
+
+// Init creates the tables and the lock row when missing.
+func (s *SQLMigrationStore) Init(ctx context.Context) error {
+	if _, err := s.db.ExecContext(ctx, fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (version BIGINT NOT NULL PRIMARY KEY, description VARCHAR(255) NOT NULL, applied_at DATETIME(6) NOT NULL)", s.records)); err != nil {
+		return err
+	}
+	if _, err := s.db.ExecContext(ctx, fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (id INT NOT NULL PRIMARY KEY, owner VARCHAR(255) NOT NULL, expires_at DATETIME(6) NOT NULL)", s.lock)); err != nil {
+		return err
+	}
+	_, err := s.db.ExecContext(ctx, fmt.Sprintf("INSERT IGNORE INTO %s (id, owner, expires_at) VALUES (?, '', ?)", s.lock), migrationLockID, time.Unix(0, 0).UTC())
+	return err
+}
+
+// Lock takes over the lock row when it is held by owner or expired.
+func (s *SQLMigrationStore) Lock(ctx context.Context, owner string, ttl time.Duration) (bool, error) {
+	now := time.Now().UTC()
+	res, err := s.db.ExecContext(ctx,
+		fmt.Sprintf("UPDATE %s SET owner = ?, expires_at = ? WHERE id = ? AND (owner = ? OR expires_at < ?)", s.lock),
+		owner, now.Add(ttl), migrationLockID, owner, now)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+func (s *SQLMigrationStore) Unlock(ctx context.Context, owner string) error {
+	_, err := s.db.ExecContext(ctx,
+		fmt.Sprintf("UPDATE %s SET owner = '', expires_at = ? WHERE id = ? AND owner = ?", s.lock),
+		time.Unix(0, 0).UTC(), migrationLockID, owner)
+	return err
+}
+
+// Applied returns the applied migrations, none when the table does not exist yet.
+func (s *SQLMigrationStore) Applied(ctx context.Context) ([]AppliedMigration, error) {
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf("SELECT version, description, applied_at FROM %s ORDER BY version", s.records))
+	if isNoSuchTable(err) {
+		return make([]AppliedMigration, 0), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make([]AppliedMigration, 0)
+	for rows.Next() {
+		var a AppliedMigration
+		// applied_at is scanned through mysql.NullTime, the DSN may not set parseTime
+		var appliedAt mysql.NullTime
+		if err := rows.Scan(&a.Version, &a.Description, &appliedAt); err != nil {
+			return nil, err
+		}
+		a.AppliedAt = appliedAt.Time
+		applied = append(applied, a)
+	}
+	return applied, rows.Err()
+}
+
+func (s *SQLMigrationStore) Record(ctx context.Context, m AppliedMigration) error {
+	_, err := s.db.ExecContext(ctx, fmt.Sprintf("INSERT INTO %s (version, description, applied_at) VALUES (?, ?, ?)", s.records),
+		m.Version, m.Description, m.AppliedAt)
+	return err
+}
+
+func (s *SQLMigrationStore) Remove(ctx context.Context, version int64) error {
+	_, err := s.db.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE version = ?", s.records), version)
+	return err
+}
+
+// isNoSuchTable reports whether err is the MariaDB error of a missing table.
+func isNoSuchTable(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == errNoSuchTable
+}
+
+// quoteIdentifier quotes a MariaDB table name.
+func quoteIdentifier(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
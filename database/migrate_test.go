@@ -0,0 +1,169 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryMigrationStore is a MigrationStore kept in memory.
+type memoryMigrationStore struct {
+	mu        sync.Mutex
+	inits     int
+	applied   map[int64]AppliedMigration
+	owner     string
+	expiresAt time.Time
+}
+
+func newMemoryMigrationStore() *memoryMigrationStore {
+	return &memoryMigrationStore{applied: make(map[int64]AppliedMigration)}
+}
+
+func (s *memoryMigrationStore) Init(context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.inits++
+	return nil
+}
+
+func (s *memoryMigrationStore) Lock(_ context.Context, owner string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.owner != "" && s.owner != owner && time.Now().Before(s.expiresAt) {
+		return false, nil
+	}
+	s.owner, s.expiresAt = owner, time.Now().Add(ttl)
+	return true, nil
+}
+
+func (s *memoryMigrationStore) Unlock(_ context.Context, owner string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.owner == owner {
+		s.owner = ""
+	}
+	return nil
+}
+
+func (s *memoryMigrationStore) Applied(context.Context) ([]AppliedMigration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	applied := make([]AppliedMigration, 0, len(s.applied))
+	for _, a := range s.applied {
+		applied = append(applied, a)
+	}
+	sort.Slice(applied, func(i, j int) bool { return applied[i].Version < applied[j].Version })
+	return applied, nil
+}
+
+func (s *memoryMigrationStore) Record(_ context.Context, m AppliedMigration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.applied[m.Version] = m
+	return nil
+}
+
+func (s *memoryMigrationStore) Remove(_ context.Context, version int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.applied, version)
+	return nil
+}
+
+func TestMigrator(t *testing.T) {
+	ctx := context.Background()
+	var ran []string
+	step := func(name string) func(context.Context) error {
+		return func(context.Context) error {
+			ran = append(ran, name)
+			return nil
+		}
+	}
+	migrations := []Migration{
+		{Version: 3, Description: "three", Up: step("up3")},
+		{Version: 1, Description: "one", Up: step("up1"), Down: step("down1")},
+		{Version: 2, Description: "two", Up: step("up2"), Down: step("down2")},
+	}
+	store := newMemoryMigrationStore()
+
+	report, err := NewMigrator(store, WithMigrationDryRun(true)).Register(migrations...).Up(ctx)
+	assert.NoError(t, err)
+	assert.True(t, report.DryRun)
+	assert.Len(t, report.Migrations, 3)
+	assert.Empty(t, ran)
+	assert.Empty(t, store.applied)
+	assert.Equal(t, 0, store.inits, "dry run only reads the store")
+
+	m := NewMigrator(store).Register(migrations...)
+	report, err = m.UpTo(ctx, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"up1", "up2"}, ran)
+	assert.Len(t, report.Migrations, 2)
+	assert.Equal(t, "", store.owner, "lock is released")
+
+	_, err = m.Up(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"up1", "up2", "up3"}, ran)
+
+	inits := store.inits
+	statuses, err := m.Status(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, inits, store.inits, "status only reads the store")
+	assert.Len(t, statuses, 3)
+	for _, s := range statuses {
+		assert.True(t, s.Applied)
+	}
+
+	_, err = m.Down(ctx, 1)
+	assert.True(t, errors.Is(err, ErrIrreversibleMigration))
+
+	delete(store.applied, 3)
+	report, err = m.Down(ctx, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"up1", "up2", "up3", "down2", "down1"}, ran)
+	assert.Equal(t, int64(2), report.Migrations[0].Version)
+	assert.Empty(t, store.applied)
+}
+
+func TestMigratorLocked(t *testing.T) {
+	store := newMemoryMigrationStore()
+	_, _ = store.Lock(context.Background(), "other", time.Minute)
+
+	_, err := NewMigrator(store).Register(Migration{Version: 1}).Up(context.Background())
+	assert.ErrorIs(t, err, ErrMigrationLocked)
+
+	_, err = NewMigrator(newMemoryMigrationStore()).Register(Migration{Version: 1}, Migration{Version: 1}).Up(context.Background())
+	assert.ErrorIs(t, err, ErrDuplicateMigration)
+}
+
+func TestMigratorHeartbeat(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryMigrationStore()
+	var contended bool
+	slow := Migration{Version: 1, Up: func(ctx context.Context) error {
+		time.Sleep(100 * time.Millisecond)
+		contended, _ = store.Lock(ctx, "other", time.Minute)
+		return nil
+	}}
+	_, err := NewMigrator(store, WithLockTTL(30*time.Millisecond)).Register(slow).Up(ctx)
+	assert.NoError(t, err)
+	assert.False(t, contended, "the lock is renewed while a migration outlives the ttl")
+	assert.Len(t, store.applied, 1)
+
+	store = newMemoryMigrationStore()
+	stolen := Migration{Version: 1, Up: func(ctx context.Context) error {
+		store.mu.Lock()
+		store.owner, store.expiresAt = "other", time.Now().Add(time.Minute)
+		store.mu.Unlock()
+		<-ctx.Done()
+		return ctx.Err()
+	}}
+	_, err = NewMigrator(store, WithLockTTL(30*time.Millisecond)).Register(stolen).Up(ctx)
+	assert.ErrorIs(t, err, ErrMigrationLocked, "a lost lock cancels the migration")
+	assert.Empty(t, store.applied)
+}
@@ -0,0 +1,245 @@
+package database
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/event"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+)
+
+// MongoConfig defines the settings of a mongo client, the `env` tags are read by LoadMongoConfig
+//
+//	cfg, err := database.LoadMongoConfig("ORDERS_MONGO_")
+//	client, err := database.NewClient(cfg.URI, database.WithMongoConfig(cfg))
+type MongoConfig struct {
+	URI     string `env:"URI" default:"mongodb://localhost:27017"`
+	AppName string `env:"APP_NAME"`
+	// ConnectTimeout bounds connecting and the initial ping of NewClient
+	ConnectTimeout  time.Duration `env:"CONNECT_TIMEOUT" default:"15s"`
+	MaxPoolSize     uint64        `env:"MAX_POOL_SIZE"`
+	MinPoolSize     uint64        `env:"MIN_POOL_SIZE"`
+	MaxConnIdleTime time.Duration `env:"MAX_CONN_IDLE_TIME"`
+	// ReadPreference is a read preference mode e.g. primary, primaryPreferred, secondary or nearest
+	ReadPreference string `env:"READ_PREFERENCE"`
+	// WriteConcern is "majority" or the number of acknowledging members
+	WriteConcern string `env:"WRITE_CONCERN"`
+	TLS          bool   `env:"TLS"`
+	// TLSCAFile is the PEM file of the certificate authorities, the system pool is used when empty
+	TLSCAFile             string `env:"TLS_CA_FILE"`
+	TLSInsecureSkipVerify bool   `env:"TLS_INSECURE_SKIP_VERIFY"`
+	// Compressors are the wire compressors in order of preference e.g. zstd,snappy,zlib
+	Compressors []string `env:"COMPRESSORS"`
+}
+
+// LoadMongoConfig returns MongoConfig read from the environment variables named prefix followed by the `env` tag,
+// e.g. MONGO_URI for the prefix "MONGO_".
+func LoadMongoConfig(prefix string) (MongoConfig, error) {
+	var cfg MongoConfig
+	if err := loadEnv(prefix, &cfg); err != nil {
+		return MongoConfig{}, err
+	}
+	return cfg, nil
+}
+
+// ClientOption configures the mongo client of NewClient
+type ClientOption func(*clientSettings) error
+
+type clientSettings struct {
+	connectTimeout time.Duration
+	opts           *options.ClientOptions
+}
+
+// WithMongoConfig applies the settings of cfg except URI, which is passed to NewClient.
+func WithMongoConfig(cfg MongoConfig) ClientOption {
+	return func(s *clientSettings) error {
+		if cfg.AppName != "" {
+			s.opts.SetAppName(cfg.AppName)
+		}
+		if cfg.ConnectTimeout > 0 {
+			s.connectTimeout = cfg.ConnectTimeout
+		}
+		if cfg.MaxPoolSize > 0 {
+			s.opts.SetMaxPoolSize(cfg.MaxPoolSize)
+		}
+		if cfg.MinPoolSize > 0 {
+			s.opts.SetMinPoolSize(cfg.MinPoolSize)
+		}
+		if cfg.MaxConnIdleTime > 0 {
+			s.opts.SetMaxConnIdleTime(cfg.MaxConnIdleTime)
+		}
+		if cfg.ReadPreference != "" {
+			mode, err := readpref.ModeFromString(cfg.ReadPreference)
+			if err != nil {
+				return err
+			}
+			rp, err := readpref.New(mode)
+			if err != nil {
+				return err
+			}
+			s.opts.SetReadPreference(rp)
+		}
+		if cfg.WriteConcern != "" {
+			wc, err := parseWriteConcern(cfg.WriteConcern)
+			if err != nil {
+				return err
+			}
+			s.opts.SetWriteConcern(wc)
+		}
+		if cfg.TLS {
+			tlsConfig, err := newTLSConfig(cfg.TLSCAFile, cfg.TLSInsecureSkipVerify)
+			if err != nil {
+				return err
+			}
+			s.opts.SetTLSConfig(tlsConfig)
+		}
+		if len(cfg.Compressors) > 0 {
+			s.opts.SetCompressors(cfg.Compressors)
+		}
+		return nil
+	}
+}
+
+// WithAppName sets the application name reported to the server and shown in its logs.
+func WithAppName(name string) ClientOption {
+	return func(s *clientSettings) error {
+		s.opts.SetAppName(name)
+		return nil
+	}
+}
+
+// WithConnectTimeout bounds connecting and the initial ping, defaults to 15 seconds.
+func WithConnectTimeout(d time.Duration) ClientOption {
+	return func(s *clientSettings) error {
+		s.connectTimeout = d
+		return nil
+	}
+}
+
+// WithPoolSize sets the min and max connections of the pool per server, zero keeps the driver default.
+func WithPoolSize(min, max uint64) ClientOption {
+	return func(s *clientSettings) error {
+		if min > 0 {
+			s.opts.SetMinPoolSize(min)
+		}
+		if max > 0 {
+			s.opts.SetMaxPoolSize(max)
+		}
+		return nil
+	}
+}
+
+// WithReadPreference sets the default read preference.
+func WithReadPreference(rp *readpref.ReadPref) ClientOption {
+	return func(s *clientSettings) error {
+		s.opts.SetReadPreference(rp)
+		return nil
+	}
+}
+
+// WithWriteConcern sets the default write concern.
+func WithWriteConcern(wc *writeconcern.WriteConcern) ClientOption {
+	return func(s *clientSettings) error {
+		s.opts.SetWriteConcern(wc)
+		return nil
+	}
+}
+
+// WithTLSConfig enables TLS using cfg.
+func WithTLSConfig(cfg *tls.Config) ClientOption {
+	return func(s *clientSettings) error {
+		s.opts.SetTLSConfig(cfg)
+		return nil
+	}
+}
+
+// WithCompressors sets the wire compressors in order of preference: zstd, snappy or zlib.
+func WithCompressors(compressors ...string) ClientOption {
+	return func(s *clientSettings) error {
+		s.opts.SetCompressors(compressors)
+		return nil
+	}
+}
+
+// WithMonitor sets the command monitor, e.g. to log slow commands or record metrics.
+func WithMonitor(monitor *event.CommandMonitor) ClientOption {
+	return func(s *clientSettings) error {
+		s.opts.SetMonitor(monitor)
+		return nil
+	}
+}
+
+// newClientSettings applies opts over the defaults for connectionString.
+func newClientSettings(connectionString string, opts []ClientOption) (*clientSettings, error) {
+	s := &clientSettings{
+		connectTimeout: timeout,
+		opts:           options.Client().ApplyURI(connectionString),
+	}
+	for _, opt := range opts {
+		if err := opt(s); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// parseWriteConcern parses "majority" or the number of acknowledging members.
+func parseWriteConcern(value string) (*writeconcern.WriteConcern, error) {
+	if value == "majority" {
+		return writeconcern.Majority(), nil
+	}
+	w, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid write concern: %v", value)
+	}
+	return &writeconcern.WriteConcern{W: w}, nil
+}
+
+// newTLSConfig returns TLS config trusting the certificate authorities of caFile, the system pool when empty.
+func newTLSConfig(caFile string, insecureSkipVerify bool) (*tls.Config, error) {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12, InsecureSkipVerify: insecureSkipVerify}
+	if caFile == "" {
+		return cfg, nil
+	}
+	pem, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificate found in %s", caFile)
+	}
+	cfg.RootCAs = pool
+	return cfg, nil
+}
+
+// Close disconnects client, waiting for in use connections until ctx is done.
+// Without a deadline on ctx it waits at most 15 seconds. A nil client is ignored.
+//
+//	<-shutdown
+//	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+//	defer cancel()
+//	if err := database.Close(ctx, client); err != nil { ... }
+func Close(ctx context.Context, client *mongo.Client) error {
+	if client == nil {
+		return nil
+	}
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	if err := client.Disconnect(ctx); err != nil && !errors.Is(err, mongo.ErrClientDisconnected) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+)
+
+func TestLoadMongoConfig(t *testing.T) {
+	t.Setenv("TEST_MONGO_URI", "mongodb://db:27017/?replicaSet=rs0")
+	t.Setenv("TEST_MONGO_APP_NAME", "orders")
+	t.Setenv("TEST_MONGO_MAX_POOL_SIZE", "50")
+	t.Setenv("TEST_MONGO_MAX_CONN_IDLE_TIME", "1m")
+	t.Setenv("TEST_MONGO_TLS", "true")
+	t.Setenv("TEST_MONGO_COMPRESSORS", "zstd, snappy")
+
+	cfg, err := LoadMongoConfig("TEST_MONGO_")
+	assert.NoError(t, err)
+	assert.Equal(t, MongoConfig{
+		URI:             "mongodb://db:27017/?replicaSet=rs0",
+		AppName:         "orders",
+		ConnectTimeout:  15 * time.Second,
+		MaxPoolSize:     50,
+		MaxConnIdleTime: time.Minute,
+		TLS:             true,
+		Compressors:     []string{"zstd", "snappy"},
+	}, cfg)
+
+	t.Setenv("TEST_MONGO_MAX_POOL_SIZE", "many")
+	_, err = LoadMongoConfig("TEST_MONGO_")
+	assert.EqualError(t, err, `invalid value of TEST_MONGO_MAX_POOL_SIZE: strconv.ParseUint: parsing "many": invalid syntax`)
+}
+
+func TestWithMongoConfig(t *testing.T) {
+	s, err := newClientSettings("mongodb://localhost:27017", []ClientOption{WithMongoConfig(MongoConfig{
+		AppName:        "orders",
+		ConnectTimeout: 5 * time.Second,
+		MinPoolSize:    2,
+		MaxPoolSize:    20,
+		ReadPreference: "secondaryPreferred",
+		WriteConcern:   "majority",
+		Compressors:    []string{"zstd"},
+	})})
+	assert.NoError(t, err)
+	assert.Equal(t, 5*time.Second, s.connectTimeout)
+	assert.Equal(t, "orders", *s.opts.AppName)
+	assert.Equal(t, uint64(2), *s.opts.MinPoolSize)
+	assert.Equal(t, uint64(20), *s.opts.MaxPoolSize)
+	assert.Equal(t, readpref.SecondaryPreferredMode, s.opts.ReadPreference.Mode())
+	assert.Equal(t, writeconcern.Majority(), s.opts.WriteConcern)
+	assert.Equal(t, []string{"zstd"}, s.opts.Compressors)
+
+	_, err = newClientSettings("mongodb://localhost:27017", []ClientOption{WithMongoConfig(MongoConfig{WriteConcern: "all"})})
+	assert.EqualError(t, err, "invalid write concern: all")
+}
@@ -2,7 +2,6 @@ package database
 
 import (
 	"context"
-	"errors"
 	"fmt"
 
 	"github.com/rs/zerolog/log"
@@ -12,41 +11,39 @@ import (
 	"time"
 )
 
+// timeout is the default connect timeout.
 const timeout = 15 * time.Second
 
 // NewClient established connection to a mongoDb instance using provided URI and auth credentials.
-func NewClient(connectionString string) (*mongo.Client, error) {
-	ctx, cancelFunc := context.WithTimeout(context.Background(), timeout)
+// opts configure the client, e.g. WithMongoConfig, the connection is checked with a ping
+// within the connect timeout.
+func NewClient(connectionString string, opts ...ClientOption) (*mongo.Client, error) {
+	settings, err := newClientSettings(connectionString, opts)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancelFunc := context.WithTimeout(context.Background(), settings.connectTimeout)
 	defer cancelFunc()
-	client, err := mongo.Connect(ctx, options.Client().ApplyURI(connectionString))
+	client, err := mongo.Connect(ctx, settings.opts)
 	if err != nil {
 		return nil, err
 	}
 	err = client.Ping(ctx, nil)
 	if err != nil {
+		_ = client.Disconnect(context.Background())
 		return nil, err
 	}
 	return client, nil
 }
 
-func IsDuplicate(err error) bool {
-	var e mongo.WriteException
-	if errors.As(err, &e) {
-		for _, we := range e.WriteErrors {
-			if we.Code == 11000 {
-				return true
-			}
-		}
-	}
-
-	return false
-}
-
 type Counter struct {
 	ID  string `bson:"_id"`
 	Seq int    `bson:"seq"`
 }
 
+// GetNextSequence increments and returns the counter of collectionName, one round trip per value.
+//
+// Deprecated: use SequenceAllocator which reserves values in blocks and returns int64.
 func GetNextSequence(ctx context.Context, counterCollection *mongo.Collection, collectionName string) (int, error) {
 	var counter Counter
 	filter := bson.M{"_id": collectionName}
@@ -71,11 +68,13 @@ const (
 )
 
 // MustCreateIndex will panic if creating an index on given collection fail
+//
+// Deprecated: declare the indexes with RegisterIndexes and apply them with EnsureIndexes which reports failures.
 func MustCreateIndex(index mongo.IndexModel, c *mongo.Collection) {
-	opts := options.CreateIndexes().SetMaxTime(10 * time.Hour)
-	ctx, cancelFunc := context.WithTimeout(context.Background(), ConnectionTimeoutInSecond*time.Hour)
+	// the context deadline bounds the index build, no separate max time is set
+	ctx, cancelFunc := context.WithTimeout(context.Background(), ConnectionTimeoutInSecond*time.Second)
 	defer cancelFunc()
-	if _, err := c.Indexes().CreateOne(ctx, index, opts); err != nil {
+	if _, err := c.Indexes().CreateOne(ctx, index); err != nil {
 		panic(fmt.Sprintf("error while applying index to collection[%s], error[%s]", c.Name(), err.Error()))
 	}
 	log.Printf("index[%v] created on collection[%s]", index.Keys, c.Name())
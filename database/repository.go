@@ -0,0 +1,294 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/a01k-io/modules/dbfilter"
+	"github.com/a01k-io/modules/logger"
+	"github.com/a01k-io/modules/paginator"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Fields maintained by Repository
+const (
+	FieldID        = "_id"
+	FieldCreatedAt = "created_at"
+	FieldUpdatedAt = "updated_at"
+	FieldDeletedAt = "deleted_at"
+	FieldVersion   = "version"
+)
+
+// ErrVersionConflict means the document was modified since it was read.
+var ErrVersionConflict = errors.New("document version conflict")
+
+// Model holds the fields maintained by Repository, embed it in the documents
+//
+//	type User struct {
+//		database.Model `bson:",inline"`
+//		Email          string `bson:"email" json:"email"`
+//	}
+type Model struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+	DeletedAt *time.Time         `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
+	// Version is incremented by every update, Update fails with ErrVersionConflict when it changed
+	Version int64 `bson:"version" json:"version"`
+}
+
+// GetModel returns m, it makes the documents embedding Model usable by Repository.
+func (m *Model) GetModel() *Model {
+	return m
+}
+
+// Document is implemented by the documents embedding Model.
+type Document interface {
+	GetModel() *Model
+}
+
+// Repository is the typed CRUD of a collection of documents T embedding Model, PT is inferred as *T.
+// Soft deleted documents are left out of every read.
+//
+//	users := database.NewRepository[User](db.Collection("users"), lg)
+//	err := users.Insert(ctx, &User{Email: "a@b.c"})
+type Repository[T any, PT interface {
+	*T
+	Document
+}] struct {
+	coll *mongo.Collection
+	lg   *logger.Wrapper
+	now  func() time.Time
+}
+
+// NewRepository returns Repository of coll logging failures through lg, a nil lg disables logging.
+func NewRepository[T any, PT interface {
+	*T
+	Document
+}](coll *mongo.Collection, lg *logger.Wrapper) *Repository[T, PT] {
+	return &Repository[T, PT]{coll: coll, lg: lg, now: func() time.Time { return time.Now().UTC() }}
+}
+
+// Collection returns the collection of the repository.
+func (r *Repository[T, PT]) Collection() *mongo.Collection {
+	return r.coll
+}
+
+// Insert stamps the id, created_at, updated_at and version of doc and inserts it.
+func (r *Repository[T, PT]) Insert(ctx context.Context, doc *T) error {
+	r.stampInsert(doc, r.now())
+	if _, err := r.coll.InsertOne(ctx, doc); err != nil {
+		return r.fail(ctx, "insert", err)
+	}
+	return nil
+}
+
+// InsertMany stamps and inserts docs in order.
+func (r *Repository[T, PT]) InsertMany(ctx context.Context, docs []*T) error {
+	if len(docs) == 0 {
+		return nil
+	}
+	now := r.now()
+	values := make([]interface{}, 0, len(docs))
+	for _, doc := range docs {
+		r.stampInsert(doc, now)
+		values = append(values, doc)
+	}
+	if _, err := r.coll.InsertMany(ctx, values); err != nil {
+		return r.fail(ctx, "insert many", err)
+	}
+	return nil
+}
+
+// FindByID returns the document with id, mongo.ErrNoDocuments when missing or soft deleted.
+func (r *Repository[T, PT]) FindByID(ctx context.Context, id interface{}) (*T, error) {
+	return r.FindOne(ctx, bson.M{FieldID: id})
+}
+
+// FindOne returns the first document matching filter, mongo.ErrNoDocuments when none.
+func (r *Repository[T, PT]) FindOne(ctx context.Context, filter bson.M, opts ...*options.FindOneOptions) (*T, error) {
+	var doc T
+	if err := r.coll.FindOne(ctx, notDeleted(filter), opts...).Decode(&doc); err != nil {
+		return nil, r.fail(ctx, "find one", err)
+	}
+	return &doc, nil
+}
+
+// FindPage returns the page of documents matching filter, see dbfilter.FindPage.
+func (r *Repository[T, PT]) FindPage(ctx context.Context, filter bson.M, params paginator.PaginationQueryParam, opts ...*options.FindOptions) (*dbfilter.PageResult[T], error) {
+	page, err := dbfilter.FindPage[T](ctx, r.coll, notDeleted(filter), params, opts...)
+	if err != nil {
+		return nil, r.fail(ctx, "find page", err)
+	}
+	return page, nil
+}
+
+// Count returns the number of documents matching filter.
+func (r *Repository[T, PT]) Count(ctx context.Context, filter bson.M) (int64, error) {
+	count, err := r.coll.CountDocuments(ctx, notDeleted(filter))
+	if err != nil {
+		return 0, r.fail(ctx, "count", err)
+	}
+	return count, nil
+}
+
+// Update sets the fields of doc when its version is unchanged, increments the version, stamps updated_at
+// and decodes the stored document into doc. created_at and deleted_at are never overwritten.
+// It returns ErrVersionConflict when the document was modified since doc was read,
+// mongo.ErrNoDocuments when it is missing or soft deleted.
+func (r *Repository[T, PT]) Update(ctx context.Context, doc *T) error {
+	m := PT(doc).GetModel()
+	update, err := versionedUpdate(doc, m.Version, r.now())
+	if err != nil {
+		return r.fail(ctx, "update", err)
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var stored T
+	err = r.coll.FindOneAndUpdate(ctx, notDeleted(bson.M{FieldID: m.ID, FieldVersion: m.Version}), update, opts).Decode(&stored)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		err = r.missingOrConflict(ctx, m.ID)
+	}
+	if err != nil {
+		return r.fail(ctx, "update", err)
+	}
+	*doc = stored
+	return nil
+}
+
+// Upsert sets the fields of doc on the document matching filter, inserting it when missing, and
+// decodes the stored document into doc. created_at is only set on insert and the version is incremented.
+// A soft deleted match is restored rather than inserting a second document next to it,
+// a live match is preferred when filter matches both.
+func (r *Repository[T, PT]) Upsert(ctx context.Context, filter bson.M, doc *T) error {
+	update, err := upsertUpdate(doc, r.now())
+	if err != nil {
+		return r.fail(ctx, "upsert", err)
+	}
+	opts := options.FindOneAndUpdate().
+		SetUpsert(true).
+		SetReturnDocument(options.After).
+		SetSort(bson.D{{Key: FieldDeletedAt, Value: 1}})
+	var stored T
+	if err := r.coll.FindOneAndUpdate(ctx, filter, update, opts).Decode(&stored); err != nil {
+		return r.fail(ctx, "upsert", err)
+	}
+	*doc = stored
+	return nil
+}
+
+// SoftDelete stamps deleted_at of the document with id, mongo.ErrNoDocuments when missing or already deleted.
+func (r *Repository[T, PT]) SoftDelete(ctx context.Context, id interface{}) error {
+	now := r.now()
+	res, err := r.coll.UpdateOne(ctx, notDeleted(bson.M{FieldID: id}), bson.M{
+		"$set": bson.M{FieldDeletedAt: now, FieldUpdatedAt: now},
+		"$inc": bson.M{FieldVersion: 1},
+	})
+	if err == nil && res.MatchedCount == 0 {
+		err = mongo.ErrNoDocuments
+	}
+	if err != nil {
+		return r.fail(ctx, "soft delete", err)
+	}
+	return nil
+}
+
+// missingOrConflict tells whether a failed versioned write missed the document or its version.
+func (r *Repository[T, PT]) missingOrConflict(ctx context.Context, id interface{}) error {
+	count, err := r.coll.CountDocuments(ctx, notDeleted(bson.M{FieldID: id}), options.Count().SetLimit(1))
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return ErrVersionConflict
+}
+
+// stampInsert sets the id, timestamps and first version of doc, values already set are kept.
+func (r *Repository[T, PT]) stampInsert(doc *T, now time.Time) {
+	m := PT(doc).GetModel()
+	if m.ID.IsZero() {
+		m.ID = primitive.NewObjectID()
+	}
+	if m.CreatedAt.IsZero() {
+		m.CreatedAt = now
+	}
+	m.UpdatedAt = now
+	if m.Version == 0 {
+		m.Version = 1
+	}
+}
+
+// fail logs err with the operation and returns it, not found errors and version conflicts are expected and not logged as errors.
+func (r *Repository[T, PT]) fail(ctx context.Context, op string, err error) error {
+	if r.lg == nil {
+		return err
+	}
+	switch {
+	case IsNotFound(err):
+	case errors.Is(err, ErrVersionConflict), IsDuplicate(err):
+		r.lg.WarnfCtx(ctx, "%s %s: %v", r.coll.Name(), op, err)
+	default:
+		r.lg.ErrorfCtx(ctx, "%s %s: %v", r.coll.Name(), op, err)
+	}
+	return err
+}
+
+// notDeleted returns a copy of filter leaving out soft deleted documents, unless filter already constrains deleted_at.
+func notDeleted(filter bson.M) bson.M {
+	query := make(bson.M, len(filter)+1)
+	for k, v := range filter {
+		query[k] = v
+	}
+	if _, ok := query[FieldDeletedAt]; !ok {
+		query[FieldDeletedAt] = nil
+	}
+	return query
+}
+
+// setFields returns the fields of doc without the id, created_at, deleted_at and version,
+// stamped with now as updated_at.
+func setFields[T any](doc *T, now time.Time) (bson.M, error) {
+	raw, err := bson.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	var set bson.M
+	if err := bson.Unmarshal(raw, &set); err != nil {
+		return nil, err
+	}
+	for _, f := range []string{FieldID, FieldCreatedAt, FieldDeletedAt, FieldVersion} {
+		delete(set, f)
+	}
+	set[FieldUpdatedAt] = now
+	return set, nil
+}
+
+// versionedUpdate returns the update of Update setting the fields of doc and moving version to the next one.
+func versionedUpdate[T any](doc *T, version int64, now time.Time) (bson.M, error) {
+	set, err := setFields(doc, now)
+	if err != nil {
+		return nil, err
+	}
+	set[FieldVersion] = version + 1
+	return bson.M{"$set": set}, nil
+}
+
+// upsertUpdate returns the update setting the fields of doc, with created_at set on insert, deleted_at
+// removed and the version incremented.
+func upsertUpdate[T any](doc *T, now time.Time) (bson.M, error) {
+	set, err := setFields(doc, now)
+	if err != nil {
+		return nil, err
+	}
+	return bson.M{
+		"$set":         set,
+		"$setOnInsert": bson.M{FieldCreatedAt: now},
+		"$unset":       bson.M{FieldDeletedAt: ""},
+		"$inc":         bson.M{FieldVersion: 1},
+	}, nil
+}
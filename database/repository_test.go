@@ -0,0 +1,72 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type repositoryUser struct {
+	Model `bson:",inline"`
+	Email string `bson:"email"`
+}
+
+func TestRepositoryStampInsert(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	created := now.Add(-time.Hour)
+	r := NewRepository[repositoryUser](nil, nil)
+
+	u := &repositoryUser{Email: "a@b.c"}
+	r.stampInsert(u, now)
+	assert.False(t, u.ID.IsZero())
+	assert.Equal(t, now, u.CreatedAt)
+	assert.Equal(t, now, u.UpdatedAt)
+	assert.Equal(t, int64(1), u.Version)
+
+	id := primitive.NewObjectID()
+	u = &repositoryUser{Model: Model{ID: id, CreatedAt: created, Version: 3}}
+	r.stampInsert(u, now)
+	assert.Equal(t, id, u.ID, "set values are kept")
+	assert.Equal(t, created, u.CreatedAt)
+	assert.Equal(t, now, u.UpdatedAt)
+	assert.Equal(t, int64(3), u.Version)
+}
+
+func TestNotDeleted(t *testing.T) {
+	filter := bson.M{"email": "a@b.c"}
+	assert.Equal(t, bson.M{"email": "a@b.c", FieldDeletedAt: nil}, notDeleted(filter))
+	assert.Equal(t, bson.M{"email": "a@b.c"}, filter, "the filter is not modified")
+
+	deleted := bson.M{FieldDeletedAt: bson.M{"$ne": nil}}
+	assert.Equal(t, deleted, notDeleted(deleted), "deleted_at constraint of the caller is kept")
+	assert.Equal(t, bson.M{FieldDeletedAt: nil}, notDeleted(nil))
+}
+
+func TestUpsertUpdate(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	u := &repositoryUser{Model: Model{ID: primitive.NewObjectID(), CreatedAt: now.Add(-time.Hour), Version: 2}, Email: "a@b.c"}
+
+	update, err := upsertUpdate(u, now)
+	assert.NoError(t, err)
+	assert.Equal(t, bson.M{
+		"$set":         bson.M{"email": "a@b.c", FieldUpdatedAt: now},
+		"$setOnInsert": bson.M{FieldCreatedAt: now},
+		"$unset":       bson.M{FieldDeletedAt: ""},
+		"$inc":         bson.M{FieldVersion: 1},
+	}, update)
+}
+
+func TestVersionedUpdate(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	deletedAt := now.Add(-time.Minute)
+	u := &repositoryUser{Model: Model{ID: primitive.NewObjectID(), CreatedAt: now, DeletedAt: &deletedAt, Version: 2}, Email: "a@b.c"}
+
+	update, err := versionedUpdate(u, u.Version, now)
+	assert.NoError(t, err)
+	assert.Equal(t, bson.M{
+		"$set": bson.M{"email": "a@b.c", FieldUpdatedAt: now, FieldVersion: int64(3)},
+	}, update, "the stored id, created_at and deleted_at are never overwritten")
+}
@@ -0,0 +1,231 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrInvalidSequenceConfig means a SequenceConfig has a negative Step or BlockSize.
+var ErrInvalidSequenceConfig = errors.New("invalid sequence config")
+
+// SequenceConfig defines the values of a sequence
+type SequenceConfig struct {
+	// Start is the first value, defaults to 1 when nil
+	Start *int64
+	// Step is the increment between values, defaults to 1, it can not be negative
+	Step int64
+	// BlockSize is the number of values reserved per round trip, defaults to 1.
+	// Reserved values which are not handed out before the process exits are skipped.
+	BlockSize int64
+	// Format is used by NextFormatted, see FormatSequence e.g. "INV-{YYYY}-{SEQ:6}"
+	Format string
+	// Yearly restarts the sequence every year, the counter id is suffixed with the year
+	Yearly bool
+}
+
+// validate reports a negative Step or BlockSize.
+func (c SequenceConfig) validate() error {
+	if c.Step < 0 {
+		return fmt.Errorf("%w: negative step %d", ErrInvalidSequenceConfig, c.Step)
+	}
+	if c.BlockSize < 0 {
+		return fmt.Errorf("%w: negative block size %d", ErrInvalidSequenceConfig, c.BlockSize)
+	}
+	return nil
+}
+
+// withDefaults returns c with the unset fields defaulted.
+func (c SequenceConfig) withDefaults() SequenceConfig {
+	if c.Start == nil {
+		start := int64(1)
+		c.Start = &start
+	}
+	if c.Step == 0 {
+		c.Step = 1
+	}
+	if c.BlockSize == 0 {
+		c.BlockSize = 1
+	}
+	return c
+}
+
+// value returns the value of the ordinal-th allocation, ordinals start at 1.
+func (c SequenceConfig) value(ordinal int64) int64 {
+	return *c.Start + (ordinal-1)*c.Step
+}
+
+// sequenceBlock is the range of reserved ordinals (next - last] not handed out yet,
+// mu is held while values are handed out or a new block is reserved.
+type sequenceBlock struct {
+	mu   sync.Mutex
+	next int64
+	last int64
+}
+
+// SequenceAllocator hands out int64 sequence values reserved in blocks from a counters collection.
+// The counter documents are those of GetNextSequence, {_id: name, seq: ordinal of the last reserved value},
+// so existing counters keep counting. It is safe for concurrent use, a reservation only blocks its own sequence.
+//
+//	invoices := database.NewSequenceAllocator(db.Collection("counters"))
+//	err := invoices.Configure("invoice", database.SequenceConfig{BlockSize: 50, Format: "INV-{YYYY}-{SEQ:6}", Yearly: true})
+//	number, err := invoices.NextFormatted(ctx, "invoice")
+type SequenceAllocator struct {
+	// mu guards configs and blocks, the blocks have their own lock
+	mu      sync.Mutex
+	configs map[string]SequenceConfig
+	blocks  map[string]*sequenceBlock
+	// reserve adds n to the counter id and returns the new ordinal
+	reserve func(ctx context.Context, id string, n int64) (int64, error)
+	now     func() time.Time
+}
+
+// NewSequenceAllocator returns SequenceAllocator reserving the values from the counters collection.
+func NewSequenceAllocator(counters *mongo.Collection) *SequenceAllocator {
+	return newSequenceAllocator(func(ctx context.Context, id string, n int64) (int64, error) {
+		var counter struct {
+			Seq int64 `bson:"seq"`
+		}
+		opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+		err := counters.FindOneAndUpdate(ctx, bson.M{"_id": id}, bson.M{"$inc": bson.M{"seq": n}}, opts).Decode(&counter)
+		return counter.Seq, err
+	})
+}
+
+func newSequenceAllocator(reserve func(ctx context.Context, id string, n int64) (int64, error)) *SequenceAllocator {
+	return &SequenceAllocator{
+		configs: make(map[string]SequenceConfig),
+		blocks:  make(map[string]*sequenceBlock),
+		reserve: reserve,
+		now:     time.Now,
+	}
+}
+
+// Configure sets the config of the sequence name, sequences which are not configured use the defaults.
+// It returns ErrInvalidSequenceConfig for a negative Step or BlockSize.
+func (a *SequenceAllocator) Configure(name string, cfg SequenceConfig) error {
+	if err := cfg.validate(); err != nil {
+		return err
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.configs[name] = cfg.withDefaults()
+	return nil
+}
+
+// Next returns the next value of the sequence name.
+func (a *SequenceAllocator) Next(ctx context.Context, name string) (int64, error) {
+	values, err := a.NextN(ctx, name, 1)
+	if err != nil {
+		return 0, err
+	}
+	return values[0], nil
+}
+
+// NextN returns the next n values of the sequence name in order, reserving at most one new block.
+// The values are consecutive unless they span a block reserved concurrently by another process.
+func (a *SequenceAllocator) NextN(ctx context.Context, name string, n int64) ([]int64, error) {
+	_, _, values, err := a.next(ctx, name, n)
+	return values, err
+}
+
+// NextFormatted returns the next value of the sequence name formatted with its Format, see FormatSequence.
+func (a *SequenceAllocator) NextFormatted(ctx context.Context, name string) (string, error) {
+	cfg, now, values, err := a.next(ctx, name, 1)
+	if err != nil {
+		return "", err
+	}
+	return FormatSequence(cfg.Format, values[0], now), nil
+}
+
+// config returns the config of the sequence name, a.mu must be held.
+func (a *SequenceAllocator) config(name string) SequenceConfig {
+	if cfg, ok := a.configs[name]; ok {
+		return cfg
+	}
+	return SequenceConfig{}.withDefaults()
+}
+
+// next hands out n values of the sequence name holding the lock of its block only.
+func (a *SequenceAllocator) next(ctx context.Context, name string, n int64) (SequenceConfig, time.Time, []int64, error) {
+	if n <= 0 {
+		return SequenceConfig{}, time.Time{}, nil, fmt.Errorf("invalid sequence count: %d", n)
+	}
+	a.mu.Lock()
+	cfg := a.config(name)
+	now := a.now()
+	id := name
+	if cfg.Yearly {
+		id = fmt.Sprintf("%s-%d", name, now.Year())
+	}
+	block, ok := a.blocks[id]
+	if !ok {
+		block = &sequenceBlock{}
+		a.blocks[id] = block
+	}
+	a.mu.Unlock()
+
+	block.mu.Lock()
+	defer block.mu.Unlock()
+
+	values := make([]int64, 0, n)
+	for block.next < block.last && int64(len(values)) < n {
+		block.next++
+		values = append(values, cfg.value(block.next))
+	}
+	if missing := n - int64(len(values)); missing > 0 {
+		size := cfg.BlockSize
+		if missing > size {
+			size = missing
+		}
+		last, err := a.reserve(ctx, id, size)
+		if err != nil {
+			return SequenceConfig{}, time.Time{}, nil, err
+		}
+		block.next, block.last = last-size, last
+		for int64(len(values)) < n {
+			block.next++
+			values = append(values, cfg.value(block.next))
+		}
+	}
+	return cfg, now, values, nil
+}
+
+var sequenceToken = regexp.MustCompile(`\{(YYYY|YY|MM|DD|SEQ)(?::(\d+))?\}`)
+
+// FormatSequence replaces the tokens of format with the date parts of t and value:
+// {YYYY}, {YY}, {MM}, {DD} and {SEQ}, or {SEQ:n} zero padded to n digits.
+// An empty format yields the plain value.
+//
+//	FormatSequence("INV-{YYYY}-{SEQ:6}", 123, t) // INV-2026-000123
+func FormatSequence(format string, value int64, t time.Time) string {
+	if format == "" {
+		return strconv.FormatInt(value, 10)
+	}
+	return sequenceToken.ReplaceAllStringFunc(format, func(token string) string {
+		m := sequenceToken.FindStringSubmatch(token)
+		switch m[1] {
+		case "YYYY":
+			return fmt.Sprintf("%04d", t.Year())
+		case "YY":
+			return fmt.Sprintf("%02d", t.Year()%100)
+		case "MM":
+			return fmt.Sprintf("%02d", int(t.Month()))
+		case "DD":
+			return fmt.Sprintf("%02d", t.Day())
+		}
+		if m[2] == "" {
+			return strconv.FormatInt(value, 10)
+		}
+		width, _ := strconv.Atoi(m[2])
+		return fmt.Sprintf("%0*d", width, value)
+	})
+}
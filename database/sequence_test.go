@@ -0,0 +1,141 @@
+package database
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.openly.dev/pointy"
+)
+
+// memoryCounters is the reserve func of SequenceAllocator over an in memory counters collection.
+type memoryCounters struct {
+	mu       sync.Mutex
+	seq      map[string]int64
+	reserves int
+}
+
+func (c *memoryCounters) reserve(_ context.Context, id string, n int64) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.seq[id] += n
+	c.reserves++
+	return c.seq[id], nil
+}
+
+func TestSequenceAllocator(t *testing.T) {
+	ctx := context.Background()
+	counters := &memoryCounters{seq: map[string]int64{"order": 41}}
+	a := newSequenceAllocator(counters.reserve)
+	assert.NoError(t, a.Configure("ticket", SequenceConfig{Start: pointy.Pointer(int64(1000)), Step: 10, BlockSize: 3}))
+
+	v, err := a.Next(ctx, "order")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(42), v, "existing counters keep counting")
+
+	values, err := a.NextN(ctx, "ticket", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{1000, 1010}, values)
+	values, err = a.NextN(ctx, "ticket", 4)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{1020, 1030, 1040, 1050}, values)
+	assert.Equal(t, int64(6), counters.seq["ticket"])
+	assert.Equal(t, 3, counters.reserves)
+
+	_, err = a.NextN(ctx, "ticket", 0)
+	assert.Error(t, err)
+}
+
+func TestSequenceAllocatorFormatted(t *testing.T) {
+	counters := &memoryCounters{seq: map[string]int64{"invoice-2026": 122}}
+	a := newSequenceAllocator(counters.reserve)
+	assert.NoError(t, a.Configure("invoice", SequenceConfig{BlockSize: 10, Format: "INV-{YYYY}-{SEQ:6}", Yearly: true}))
+	a.now = func() time.Time { return time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC) }
+
+	number, err := a.NextFormatted(context.Background(), "invoice")
+	assert.NoError(t, err)
+	assert.Equal(t, "INV-2026-000123", number)
+
+	a.now = func() time.Time { return time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC) }
+	number, err = a.NextFormatted(context.Background(), "invoice")
+	assert.NoError(t, err)
+	assert.Equal(t, "INV-2027-000001", number)
+}
+
+func TestSequenceAllocatorConcurrent(t *testing.T) {
+	counters := &memoryCounters{seq: map[string]int64{}}
+	a := newSequenceAllocator(counters.reserve)
+	assert.NoError(t, a.Configure("id", SequenceConfig{BlockSize: 7}))
+
+	var wg sync.WaitGroup
+	seen := make(chan int64, 100)
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := a.Next(context.Background(), "id")
+			assert.NoError(t, err)
+			seen <- v
+		}()
+	}
+	wg.Wait()
+	close(seen)
+
+	unique := make(map[int64]bool)
+	for v := range seen {
+		unique[v] = true
+	}
+	assert.Len(t, unique, 100)
+}
+
+func TestSequenceAllocatorConfigure(t *testing.T) {
+	counters := &memoryCounters{seq: map[string]int64{}}
+	a := newSequenceAllocator(counters.reserve)
+	assert.NoError(t, a.Configure("zero", SequenceConfig{Start: pointy.Pointer(int64(0))}))
+	values, err := a.NextN(context.Background(), "zero", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{0, 1}, values, "a sequence can start at 0")
+
+	assert.ErrorIs(t, a.Configure("down", SequenceConfig{Step: -1}), ErrInvalidSequenceConfig)
+	assert.ErrorIs(t, a.Configure("block", SequenceConfig{BlockSize: -5}), ErrInvalidSequenceConfig)
+}
+
+func TestSequenceAllocatorReservesPerSequence(t *testing.T) {
+	counters := &memoryCounters{seq: map[string]int64{}}
+	release := make(chan struct{})
+	a := newSequenceAllocator(func(ctx context.Context, id string, n int64) (int64, error) {
+		if id == "slow" {
+			<-release
+		}
+		return counters.reserve(ctx, id, n)
+	})
+
+	slow := make(chan int64)
+	go func() {
+		v, _ := a.Next(context.Background(), "slow")
+		slow <- v
+	}()
+
+	done := make(chan int64)
+	go func() {
+		v, _ := a.Next(context.Background(), "fast")
+		done <- v
+	}()
+	select {
+	case v := <-done:
+		assert.Equal(t, int64(1), v)
+	case <-time.After(time.Second):
+		t.Fatal("a slow reservation blocks other sequences")
+	}
+	close(release)
+	assert.Equal(t, int64(1), <-slow)
+}
+
+func TestFormatSequence(t *testing.T) {
+	day := time.Date(2026, 7, 9, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, "7", FormatSequence("", 7, day))
+	assert.Equal(t, "PO26070900042/42", FormatSequence("PO{YY}{MM}{DD}{SEQ:5}/{SEQ}", 42, day))
+	assert.Equal(t, "X-1234", FormatSequence("X-{SEQ:2}", 1234, day))
+}
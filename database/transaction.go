@@ -0,0 +1,207 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+)
+
+const (
+	// TransientTransactionErrorLabel marks errors after which the whole transaction can be retried.
+	TransientTransactionErrorLabel = "TransientTransactionError"
+	// UnknownTransactionCommitResultLabel marks commit errors after which the commit can be retried.
+	UnknownTransactionCommitResultLabel = "UnknownTransactionCommitResult"
+
+	// illegalOperationCode is returned by a standalone server for transaction commands.
+	illegalOperationCode = 20
+)
+
+// ErrTransactionsNotSupported is matched by TransactionNotSupportedError.
+var ErrTransactionsNotSupported = errors.New("transactions require a replica set or sharded cluster")
+
+// TransactionNotSupportedError is returned by WithTransaction when the deployment is a standalone server.
+type TransactionNotSupportedError struct {
+	Err error
+}
+
+func (e *TransactionNotSupportedError) Error() string {
+	return ErrTransactionsNotSupported.Error() + ": " + e.Err.Error()
+}
+
+func (e *TransactionNotSupportedError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports ErrTransactionsNotSupported as the target.
+func (e *TransactionNotSupportedError) Is(target error) bool {
+	return target == ErrTransactionsNotSupported
+}
+
+// TransactionOptions configures WithTransaction, zero values fall back to DefaultTransactionOptions.
+type TransactionOptions struct {
+	ReadConcern    *readconcern.ReadConcern
+	WriteConcern   *writeconcern.WriteConcern
+	ReadPreference *readpref.ReadPref
+	// MaxCommitTime bounds a single commit on the server, zero leaves it to the server.
+	MaxCommitTime time.Duration
+	// MaxAttempts bounds the runs of fn, and the commits of one run.
+	MaxAttempts int
+	// InitialBackoff is the wait before the first retry, doubled on every retry up to MaxBackoff.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// DefaultTransactionOptions are majority read and write concerns on the primary with 3 attempts.
+var DefaultTransactionOptions = TransactionOptions{
+	ReadConcern:    readconcern.Majority(),
+	WriteConcern:   writeconcern.Majority(),
+	ReadPreference: readpref.Primary(),
+	MaxAttempts:    3,
+	InitialBackoff: 50 * time.Millisecond,
+	MaxBackoff:     time.Second,
+}
+
+// withDefaults returns opts with the zero values taken from DefaultTransactionOptions.
+func (opts *TransactionOptions) withDefaults() TransactionOptions {
+	o := DefaultTransactionOptions
+	if opts == nil {
+		return o
+	}
+	if opts.ReadConcern != nil {
+		o.ReadConcern = opts.ReadConcern
+	}
+	if opts.WriteConcern != nil {
+		o.WriteConcern = opts.WriteConcern
+	}
+	if opts.ReadPreference != nil {
+		o.ReadPreference = opts.ReadPreference
+	}
+	if opts.MaxCommitTime > 0 {
+		o.MaxCommitTime = opts.MaxCommitTime
+	}
+	if opts.MaxAttempts > 0 {
+		o.MaxAttempts = opts.MaxAttempts
+	}
+	if opts.InitialBackoff > 0 {
+		o.InitialBackoff = opts.InitialBackoff
+	}
+	if opts.MaxBackoff > 0 {
+		o.MaxBackoff = opts.MaxBackoff
+	}
+	return o
+}
+
+// backoff returns the wait before retry attempt, attempt starts at 1.
+func (opts TransactionOptions) backoff(attempt int) time.Duration {
+	d := opts.InitialBackoff
+	for i := 1; i < attempt && d < opts.MaxBackoff; i++ {
+		d *= 2
+	}
+	if d > opts.MaxBackoff {
+		d = opts.MaxBackoff
+	}
+	return d
+}
+
+// WithTransaction runs fn in a transaction of a new session of client, opts may be nil.
+// fn must use sessCtx for every operation of the transaction and may be run more than once:
+// the transaction is retried on TransientTransactionError and the commit on UnknownTransactionCommitResult,
+// at most opts.MaxAttempts times with exponential backoff. A standalone deployment yields TransactionNotSupportedError.
+//
+//	err := database.WithTransaction(ctx, client, func(sessCtx mongo.SessionContext) error {
+//		if _, err := orders.InsertOne(sessCtx, order); err != nil {
+//			return err
+//		}
+//		_, err := stock.UpdateOne(sessCtx, filter, update)
+//		return err
+//	}, nil)
+func WithTransaction(ctx context.Context, client *mongo.Client, fn func(sessCtx mongo.SessionContext) error, opts *TransactionOptions) error {
+	o := opts.withDefaults()
+	session, err := client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(context.Background())
+
+	txnOpts := options.Transaction().
+		SetReadConcern(o.ReadConcern).
+		SetWriteConcern(o.WriteConcern).
+		SetReadPreference(o.ReadPreference)
+	if o.MaxCommitTime > 0 {
+		txnOpts.SetMaxCommitTime(&o.MaxCommitTime)
+	}
+
+	for attempt := 1; ; attempt++ {
+		err = mongo.WithSession(ctx, session, func(sessCtx mongo.SessionContext) error {
+			return runTransaction(sessCtx, session, fn, txnOpts, o)
+		})
+		if err == nil {
+			return nil
+		}
+		if isTransactionNotSupported(err) {
+			return &TransactionNotSupportedError{Err: err}
+		}
+		if !hasErrorLabel(err, TransientTransactionErrorLabel) || attempt >= o.MaxAttempts {
+			return err
+		}
+		if err := sleep(ctx, o.backoff(attempt)); err != nil {
+			return err
+		}
+	}
+}
+
+// runTransaction runs fn in a new transaction of session and commits it, retrying the commit on UnknownTransactionCommitResult.
+func runTransaction(sessCtx mongo.SessionContext, session mongo.Session, fn func(sessCtx mongo.SessionContext) error, txnOpts *options.TransactionOptions, o TransactionOptions) error {
+	if err := session.StartTransaction(txnOpts); err != nil {
+		return err
+	}
+	if err := fn(sessCtx); err != nil {
+		_ = session.AbortTransaction(context.Background())
+		return err
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := session.CommitTransaction(sessCtx)
+		if err == nil || !hasErrorLabel(err, UnknownTransactionCommitResultLabel) || attempt >= o.MaxAttempts {
+			return err
+		}
+		if err := sleep(sessCtx, o.backoff(attempt)); err != nil {
+			return err
+		}
+	}
+}
+
+// hasErrorLabel reports whether err carries label.
+func hasErrorLabel(err error, label string) bool {
+	var labeled mongo.LabeledError
+	return errors.As(err, &labeled) && labeled.HasErrorLabel(label)
+}
+
+// isTransactionNotSupported reports whether err is the rejection of a transaction by a standalone server.
+// Server errors are matched by illegalOperationCode, the message is only checked for errors without a code.
+func isTransactionNotSupported(err error) bool {
+	var serverErr mongo.ServerError
+	if errors.As(err, &serverErr) {
+		return serverErr.HasErrorCode(illegalOperationCode)
+	}
+	return strings.Contains(err.Error(), "Transaction numbers are only allowed")
+}
+
+// sleep waits for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,40 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestTransactionOptionsBackoff(t *testing.T) {
+	o := (&TransactionOptions{InitialBackoff: 100 * time.Millisecond, MaxBackoff: 350 * time.Millisecond}).withDefaults()
+	assert.Equal(t, 100*time.Millisecond, o.backoff(1))
+	assert.Equal(t, 200*time.Millisecond, o.backoff(2))
+	assert.Equal(t, 350*time.Millisecond, o.backoff(3))
+	assert.Equal(t, 350*time.Millisecond, o.backoff(10))
+	assert.Equal(t, 3, o.MaxAttempts)
+}
+
+func TestTransactionErrorClassification(t *testing.T) {
+	transient := mongo.CommandError{Code: 112, Name: "WriteConflict", Labels: []string{TransientTransactionErrorLabel}}
+	assert.True(t, hasErrorLabel(transient, TransientTransactionErrorLabel))
+	assert.False(t, hasErrorLabel(transient, UnknownTransactionCommitResultLabel))
+	assert.False(t, hasErrorLabel(errors.New("boom"), TransientTransactionErrorLabel))
+
+	standalone := mongo.CommandError{Code: 20, Name: "IllegalOperation", Message: "Transaction numbers are only allowed on a replica set member or mongos"}
+	assert.True(t, isTransactionNotSupported(standalone))
+	assert.True(t, isTransactionNotSupported(mongo.CommandError{Code: 20, Message: "Transaction numbers are not allowed here"}), "matched by code, not message")
+	assert.True(t, isTransactionNotSupported(mongo.WriteException{WriteErrors: mongo.WriteErrors{{Code: 20, Message: "other"}}}))
+	assert.False(t, isTransactionNotSupported(mongo.CommandError{Code: 112, Message: "Transaction numbers are only allowed on a replica set member or mongos"}))
+	assert.True(t, isTransactionNotSupported(fmt.Errorf("commit: %w", errors.New("Transaction numbers are only allowed on a replica set member or mongos"))), "message checked without a code")
+	assert.False(t, isTransactionNotSupported(errors.New("boom")))
+
+	err := error(&TransactionNotSupportedError{Err: standalone})
+	assert.ErrorIs(t, err, ErrTransactionsNotSupported)
+	var cmdErr mongo.CommandError
+	assert.True(t, errors.As(err, &cmdErr))
+}
@@ -0,0 +1,199 @@
+package dbfilter
+
+import (
+	"regexp"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Filter is an immutable mongo filter builder, every method returns a new Filter
+// and leaves the receiver untouched. Conditions keep the order they were added in,
+// conditions on the same field are merged into one operator document.
+//
+//	f := NewFilter().Eq("tenant_id", tenantID).In("status", "active", "pending").DateRange("created_at", from, to)
+type Filter struct {
+	conds bson.D
+}
+
+// NewFilter returns an empty Filter.
+func NewFilter() Filter {
+	return Filter{}
+}
+
+// Eq matches documents where field equals v.
+func (f Filter) Eq(field string, v interface{}) Filter {
+	return f.with(field, EqOp, v)
+}
+
+// Ne matches documents where field does not equal v.
+func (f Filter) Ne(field string, v interface{}) Filter {
+	return f.with(field, NeOp, v)
+}
+
+// In matches documents where field equals any of values.
+func (f Filter) In(field string, values ...interface{}) Filter {
+	return f.with(field, InOp, bson.A(values))
+}
+
+// Nin matches documents where field equals none of values.
+func (f Filter) Nin(field string, values ...interface{}) Filter {
+	return f.with(field, NinOp, bson.A(values))
+}
+
+// Gt matches documents where field is greater than v.
+func (f Filter) Gt(field string, v interface{}) Filter {
+	return f.with(field, GTOp, v)
+}
+
+// Gte matches documents where field is greater than or equal to v.
+func (f Filter) Gte(field string, v interface{}) Filter {
+	return f.with(field, GTEOp, v)
+}
+
+// Lt matches documents where field is less than v.
+func (f Filter) Lt(field string, v interface{}) Filter {
+	return f.with(field, LTOp, v)
+}
+
+// Lte matches documents where field is less than or equal to v.
+func (f Filter) Lte(field string, v interface{}) Filter {
+	return f.with(field, LTEOp, v)
+}
+
+// Exists matches documents which have, or have not, the field.
+func (f Filter) Exists(field string, exists bool) Filter {
+	return f.with(field, ExistsOp, exists)
+}
+
+// Regex matches documents where field contains value, value is escaped and matched literally.
+// options are the mongo regex options e.g. "i" for case insensitive match.
+func (f Filter) Regex(field, value, options string) Filter {
+	return f.regex(field, regexp.QuoteMeta(value), options)
+}
+
+// Prefix matches documents where field starts with value, value is escaped and matched literally.
+func (f Filter) Prefix(field, value, options string) Filter {
+	return f.regex(field, "^"+regexp.QuoteMeta(value), options)
+}
+
+func (f Filter) regex(field, pattern, options string) Filter {
+	f = f.with(field, RegexOp, pattern)
+	if options != "" {
+		f = f.with(field, RegexOptionsOp, options)
+	}
+	return f
+}
+
+// ElemMatch matches documents where at least one element of the array field matches sub.
+func (f Filter) ElemMatch(field string, sub Filter) Filter {
+	return f.with(field, ElemMatchOp, sub.D())
+}
+
+// DateRange matches documents where field is in [from, to). Zero from or to leaves that side open.
+func (f Filter) DateRange(field string, from, to time.Time) Filter {
+	if !from.IsZero() {
+		f = f.Gte(field, from)
+	}
+	if !to.IsZero() {
+		f = f.Lt(field, to)
+	}
+	return f
+}
+
+// OnDay matches documents where field is within the calendar day of day in its location.
+func (f Filter) OnDay(field string, day time.Time) Filter {
+	start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	return f.DateRange(field, start, start.AddDate(0, 0, 1))
+}
+
+// And matches documents matching all of filters.
+func (f Filter) And(filters ...Filter) Filter {
+	return f.logical(AndOp, filters)
+}
+
+// Or matches documents matching any of filters.
+func (f Filter) Or(filters ...Filter) Filter {
+	return f.logical(OrOp, filters)
+}
+
+// Not matches documents not matching sub.
+func (f Filter) Not(sub Filter) Filter {
+	return f.logical(NorOp, []Filter{sub})
+}
+
+func (f Filter) logical(op Operator, filters []Filter) Filter {
+	if len(filters) == 0 {
+		return f
+	}
+	list := make(bson.A, 0, len(filters))
+	for _, v := range filters {
+		list = append(list, v.D())
+	}
+	conds := make(bson.D, len(f.conds), len(f.conds)+1)
+	copy(conds, f.conds)
+
+	// a second $or or $nor can not share the key of the first one, it is and-ed instead.
+	if op != AndOp && hasKey(conds, string(op)) {
+		list = bson.A{bson.D{{Key: string(op), Value: list}}}
+		op = AndOp
+	}
+	for i, e := range conds {
+		if e.Key == string(op) {
+			existing := e.Value.(bson.A)
+			merged := make(bson.A, len(existing), len(existing)+len(list))
+			copy(merged, existing)
+			conds[i].Value = append(merged, list...)
+			return Filter{conds: conds}
+		}
+	}
+	return Filter{conds: append(conds, bson.E{Key: string(op), Value: list})}
+}
+
+func hasKey(d bson.D, key string) bool {
+	for _, e := range d {
+		if e.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// with returns a copy of f with the operator condition added to field.
+func (f Filter) with(field string, op Operator, v interface{}) Filter {
+	conds := make(bson.D, len(f.conds), len(f.conds)+1)
+	copy(conds, f.conds)
+	for i, e := range conds {
+		if e.Key != field {
+			continue
+		}
+		if ops, ok := e.Value.(bson.D); ok {
+			merged := make(bson.D, len(ops), len(ops)+1)
+			copy(merged, ops)
+			conds[i].Value = append(merged, bson.E{Key: string(op), Value: v})
+			return Filter{conds: conds}
+		}
+	}
+	return Filter{conds: append(conds, bson.E{Key: field, Value: bson.D{{Key: string(op), Value: v}}})}
+}
+
+// IsEmpty returns true when no condition has been added.
+func (f Filter) IsEmpty() bool {
+	return len(f.conds) == 0
+}
+
+// D returns the filter as ordered mongo document.
+func (f Filter) D() bson.D {
+	conds := make(bson.D, len(f.conds))
+	copy(conds, f.conds)
+	return conds
+}
+
+// M returns the filter as bson.M to be used with BuildQuery.
+func (f Filter) M() bson.M {
+	m := make(bson.M, len(f.conds))
+	for _, e := range f.conds {
+		m[e.Key] = e.Value
+	}
+	return m
+}
@@ -0,0 +1,91 @@
+package dbfilter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestFilterBuilder(t *testing.T) {
+	from := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name   string
+		filter Filter
+		want   bson.D
+	}{
+		{
+			name:   "empty",
+			filter: NewFilter(),
+			want:   bson.D{},
+		},
+		{
+			name: "conditions keep order and merge per field",
+			filter: NewFilter().
+				Eq("tenant_id", "t1").
+				Gte("amount", 10).
+				In("status", "active", "pending").
+				Lte("amount", 20),
+			want: bson.D{
+				{Key: "tenant_id", Value: bson.D{{Key: "$eq", Value: "t1"}}},
+				{Key: "amount", Value: bson.D{{Key: "$gte", Value: 10}, {Key: "$lte", Value: 20}}},
+				{Key: "status", Value: bson.D{{Key: "$in", Value: bson.A{"active", "pending"}}}},
+			},
+		},
+		{
+			name:   "regex is escaped",
+			filter: NewFilter().Regex("name", "a.b*", "i").Prefix("code", "(x)", ""),
+			want: bson.D{
+				{Key: "name", Value: bson.D{{Key: "$regex", Value: `a\.b\*`}, {Key: "$options", Value: "i"}}},
+				{Key: "code", Value: bson.D{{Key: "$regex", Value: `^\(x\)`}}},
+			},
+		},
+		{
+			name:   "date helpers",
+			filter: NewFilter().DateRange("created_at", from, time.Time{}).OnDay("paid_at", from.Add(5*time.Hour)),
+			want: bson.D{
+				{Key: "created_at", Value: bson.D{{Key: "$gte", Value: from}}},
+				{Key: "paid_at", Value: bson.D{{Key: "$gte", Value: from}, {Key: "$lt", Value: from.AddDate(0, 0, 1)}}},
+			},
+		},
+		{
+			name: "logical operators",
+			filter: NewFilter().
+				ElemMatch("items", NewFilter().Eq("sku", "a").Gt("qty", 1)).
+				Or(NewFilter().Exists("deleted_at", false), NewFilter().Eq("deleted_at", nil)).
+				Or(NewFilter().Ne("status", "x")).
+				Not(NewFilter().Eq("hidden", true)),
+			want: bson.D{
+				{Key: "items", Value: bson.D{{Key: "$elemMatch", Value: bson.D{
+					{Key: "sku", Value: bson.D{{Key: "$eq", Value: "a"}}},
+					{Key: "qty", Value: bson.D{{Key: "$gt", Value: 1}}},
+				}}}},
+				{Key: "$or", Value: bson.A{
+					bson.D{{Key: "deleted_at", Value: bson.D{{Key: "$exists", Value: false}}}},
+					bson.D{{Key: "deleted_at", Value: bson.D{{Key: "$eq", Value: nil}}}},
+				}},
+				{Key: "$and", Value: bson.A{
+					bson.D{{Key: "$or", Value: bson.A{bson.D{{Key: "status", Value: bson.D{{Key: "$ne", Value: "x"}}}}}}},
+				}},
+				{Key: "$nor", Value: bson.A{bson.D{{Key: "hidden", Value: bson.D{{Key: "$eq", Value: true}}}}}},
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert.Equal(t, c.want, c.filter.D())
+		})
+	}
+}
+
+func TestFilterBuilderImmutable(t *testing.T) {
+	base := NewFilter().Gte("amount", 10)
+	upper := base.Lte("amount", 20)
+	other := base.Lte("amount", 30)
+
+	assert.Equal(t, bson.D{{Key: "amount", Value: bson.D{{Key: "$gte", Value: 10}}}}, base.D())
+	assert.Equal(t, bson.M{"amount": bson.D{{Key: "$gte", Value: 10}, {Key: "$lte", Value: 20}}}, upper.M())
+	assert.Equal(t, bson.M{"amount": bson.D{{Key: "$gte", Value: 10}, {Key: "$lte", Value: 30}}}, other.M())
+}
@@ -0,0 +1,48 @@
+package dbfilter
+
+import (
+	"github.com/a01k-io/modules/paginator"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// filterOperatorMapping contain mapping between filter operator and mongo operator.
+var filterOperatorMapping = map[paginator.FilterOperator]Operator{
+	paginator.FilterEq:  EqOp,
+	paginator.FilterNe:  NeOp,
+	paginator.FilterGt:  GTOp,
+	paginator.FilterGte: GTEOp,
+	paginator.FilterLt:  LTOp,
+	paginator.FilterLte: LTEOp,
+	paginator.FilterIn:  InOp,
+	paginator.FilterNin: NinOp,
+}
+
+// CompileFilter converts filter conditions parsed by paginator.FilterSchema to mongo query.
+// Conditions on the same field are merged, e.g. amount:gte:10 and amount:lte:20 yields
+// {"amount": {"$gte": 10, "$lte": 20}}. A repeated operator on a field is combined with $and,
+// e.g. status:ne:a and status:ne:b yields {"$and": [{"status": {"$ne": "a"}}, {"status": {"$ne": "b"}}]},
+// so all conditions apply as they do with paginator.BuildFilterQuery.
+func CompileFilter(conditions []paginator.FilterCondition) bson.M {
+	filter := bson.M{}
+	var repeated bson.A
+	for _, c := range conditions {
+		operator, ok := filterOperatorMapping[c.Operator]
+		if !ok {
+			continue
+		}
+		field, ok := filter[c.Field].(bson.M)
+		if !ok {
+			field = bson.M{}
+			filter[c.Field] = field
+		}
+		if _, ok := field[string(operator)]; ok {
+			repeated = append(repeated, bson.M{c.Field: bson.M{string(operator): c.Value}})
+			continue
+		}
+		field[string(operator)] = c.Value
+	}
+	if len(repeated) != 0 {
+		return bson.M{string(AndOp): append(bson.A{filter}, repeated...)}
+	}
+	return filter
+}
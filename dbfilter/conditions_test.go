@@ -0,0 +1,40 @@
+package dbfilter
+
+import (
+	"testing"
+
+	"github.com/a01k-io/modules/paginator"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestCompileFilter(t *testing.T) {
+	got := CompileFilter([]paginator.FilterCondition{
+		{Field: "status", Operator: paginator.FilterEq, Value: "active"},
+		{Field: "amount", Operator: paginator.FilterGte, Value: int64(10)},
+		{Field: "amount", Operator: paginator.FilterLte, Value: int64(20)},
+		{Field: "tags", Operator: paginator.FilterIn, Value: []interface{}{"a", "b"}},
+	})
+	assert.Equal(t, bson.M{
+		"status": bson.M{"$eq": "active"},
+		"amount": bson.M{"$gte": int64(10), "$lte": int64(20)},
+		"tags":   bson.M{"$in": []interface{}{"a", "b"}},
+	}, got)
+}
+
+func TestCompileFilterRepeatedOperator(t *testing.T) {
+	got := CompileFilter([]paginator.FilterCondition{
+		{Field: "status", Operator: paginator.FilterNe, Value: "a"},
+		{Field: "amount", Operator: paginator.FilterGte, Value: int64(10)},
+		{Field: "status", Operator: paginator.FilterNe, Value: "b"},
+		{Field: "status", Operator: paginator.FilterNe, Value: "c"},
+	})
+	assert.Equal(t, bson.M{"$and": bson.A{
+		bson.M{
+			"status": bson.M{"$ne": "a"},
+			"amount": bson.M{"$gte": int64(10)},
+		},
+		bson.M{"status": bson.M{"$ne": "b"}},
+		bson.M{"status": bson.M{"$ne": "c"}},
+	}}, got)
+}
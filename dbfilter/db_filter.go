@@ -1,6 +1,7 @@
 package dbfilter
 
 import (
+	"context"
 	"errors"
 	"fmt"
 
@@ -8,6 +9,7 @@ import (
 	"github.com/a01k-io/modules/stringops"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
@@ -37,22 +39,83 @@ const (
 
 	// GTOp greater than operator.
 	GTOp Operator = "$gt"
+
+	// LTEOp less than or equal operator.
+	LTEOp Operator = "$lte"
+
+	// GTEOp greater than or equal operator.
+	GTEOp Operator = "$gte"
+
+	// EqOp equal operator.
+	EqOp Operator = "$eq"
+
+	// NeOp not equal operator.
+	NeOp Operator = "$ne"
+
+	// InOp in array operator.
+	InOp Operator = "$in"
+
+	// NinOp not in array operator.
+	NinOp Operator = "$nin"
+
+	// ExistsOp field exists operator.
+	ExistsOp Operator = "$exists"
+
+	// RegexOp regular expression operator.
+	RegexOp Operator = "$regex"
+
+	// RegexOptionsOp regular expression options operator.
+	RegexOptionsOp Operator = "$options"
+
+	// ElemMatchOp array element match operator.
+	ElemMatchOp Operator = "$elemMatch"
+
+	// AndOp logical and operator.
+	AndOp Operator = "$and"
+
+	// OrOp logical or operator.
+	OrOp Operator = "$or"
+
+	// NorOp logical nor operator.
+	NorOp Operator = "$nor"
+
+	// TextOp text search operator.
+	TextOp Operator = "$text"
 )
 
+// IDField is the field appended to every sort as the keyset tie-breaker.
+const IDField = "_id"
+
 type QueryBuilder struct {
 	Query interface{}
 	Sort  []SortType
 	Limit int64
 	Skip  int64
+	// Reversed is set when Sort was inverted to fetch a previous page,
+	// the results must be passed through paginator.Reverse before display.
+	Reversed bool
+	// TextScore is set when the results are ordered by text search relevance, Sort only holds
+	// the tie-breaker and the score is added to the projection. Relevance pages are offset pages.
+	TextScore bool
+	// Projection selects the returned fields, nil returns the full documents.
+	Projection bson.D
+	// Keyset is the condition of cursor and last_id pages selecting the rows after the boundary row,
+	// Query holds Filter combined with Keyset. Both are nil for offset pages.
+	Keyset bson.M
+	// Filter is Query without Keyset, the total count of keyset pages matches it.
+	Filter bson.M
 }
 
 var (
 	// ErrInvalidShortType means the short type is invalid.
 	ErrInvalidShortType      = errors.New("invalid sort type")
 	ErrorUnableToParseLastID = errors.New("unable to parse last_id")
+	// ErrCursorSortMismatch means the cursor was issued for a different sort_by.
+	ErrCursorSortMismatch = paginator.ErrCursorSortMismatch
 	//PaginationTypeQueryMapping contain mapping between paginationtype and operator.
 	PaginationTypeQueryMapping = map[string]Operator{
 		"next":     LTOp,
+		"prev":     GTOp,
 		"previous": GTOp,
 	}
 	SortingTypeQueryMapping = map[string]SortDirection{
@@ -73,12 +136,14 @@ func (f *QueryBuilder) ToMongo(opts *options.FindOptions) (bson.M, error) {
 	}
 
 	// Make sort query mongo compatible
-	if f.Sort != nil {
-		sort := make(bson.D, 0, len(f.Sort))
-		for _, v := range f.Sort {
-			sort = append(sort, bson.E{Key: v.Name, Value: v.Direction})
-		}
-		opts.SetSort(sort)
+	if f.Sort != nil || f.TextScore {
+		opts.SetSort(f.mongoSort())
+	}
+	if f.Projection != nil {
+		opts.SetProjection(f.Projection)
+	}
+	if f.TextScore {
+		opts.SetProjection(withTextScore(opts.Projection))
 	}
 
 	if f.Limit > 0 {
@@ -92,6 +157,34 @@ func (f *QueryBuilder) ToMongo(opts *options.FindOptions) (bson.M, error) {
 	return filter, nil
 }
 
+// sortDocument converts sort to mongo sort document keeping its order.
+func sortDocument(sort []SortType) bson.D {
+	doc := make(bson.D, 0, len(sort))
+	for _, v := range sort {
+		doc = append(doc, bson.E{Key: v.Name, Value: v.Direction})
+	}
+	return doc
+}
+
+// projectionDocument converts projection to mongo projection document, nil when it returns the full documents.
+func projectionDocument(projection *paginator.Projection) bson.D {
+	if projection.IsEmpty() {
+		return nil
+	}
+	value := 1
+	if projection.Exclude {
+		value = 0
+	}
+	doc := make(bson.D, 0, len(projection.Fields))
+	for _, f := range projection.Fields {
+		doc = append(doc, bson.E{Key: f, Value: value})
+	}
+	return doc
+}
+
+// GetAggregationQuery converts filter and options to $match, $sort, $skip and $limit stages.
+//
+// Deprecated: use QueryBuilder.Pipeline which keeps stage and key order and supports joins and $facet.
 func GetAggregationQuery(filter bson.M, options *options.FindOptions) []bson.M {
 	query := []bson.M{{"$match": filter}}
 
@@ -109,29 +202,27 @@ func GetAggregationQuery(filter bson.M, options *options.FindOptions) []bson.M {
 	return query
 }
 
-// BuildQuery builds query using pagination params and filter
+// BuildQuery builds query using pagination params and filter.
+// Sort keys are mapped through paginationParameter.SortSpec when set and the page size is
+// moved into the range of paginationParameter.Policy. In CountFree mode one extra row is fetched.
+// Sort always ends with IDField as tie-breaker. When params carry a decoded cursor
+// the query selects the rows after the cursor tuple in sort order, for previous pages
+// the sort is inverted and QueryBuilder.Reversed is set.
+// The search term of the params is matched per the policy SearchSpec, without sort_by a text search
+// is ordered by relevance and sets QueryBuilder.TextScore. The fields param sets QueryBuilder.Projection,
+// the sort fields are always returned so cursors can be issued.
 func BuildQuery(filter bson.M, paginationParameter paginator.PaginationQueryParam) (*QueryBuilder, error) {
 	var qb QueryBuilder
-	var skipCount int64
-	// var paginationQ PaginationQ
-	if !stringops.IsBlank(paginationParameter.LastID) {
-		objectID, e := primitive.ObjectIDFromHex(paginationParameter.LastID)
-		if e != nil {
-			return nil, ErrorUnableToParseLastID
-		}
-		skipCount = 0
-		operator := PaginationTypeQueryMapping[string(paginationParameter.Type)]
-		filter["_id"] = bson.M{
-			string(operator): objectID,
-		}
-	} else {
-		skipCount = getSkipCount(paginationParameter)
+
+	if search := searchCondition(paginationParameter); search != nil {
+		filter = withCondition(filter, search)
+	}
+	qb.TextScore = paginationParameter.IsRelevanceSorted()
+	if qb.TextScore && paginationParameter.DecodedCursor != nil {
+		return nil, paginator.ErrSearchCursor
 	}
-	qb.Query = filter
-	qb.Skip = skipCount
-	qb.Limit = paginationParameter.PageSize
 
-	sortingFields, errFields := paginationParameter.GetSortingFields()
+	sortingFields, errFields := paginationParameter.ResolveSortingFields()
 	if errFields != nil {
 		return nil, errFields
 	}
@@ -142,14 +233,139 @@ func BuildQuery(filter bson.M, paginationParameter paginator.PaginationQueryPara
 			Direction: getSortTypeFromOrderBy(v.OrderBy),
 		})
 	}
+	qb.Sort = withTieBreaker(qb.Sort)
+	qb.Limit = paginationParameter.FetchLimit()
 
-	if len(qb.Sort) == 0 {
-		qb.Sort = []SortType{{Name: "_id", Direction: Desc}}
+	switch {
+	case paginationParameter.DecodedCursor != nil:
+		cursor := paginationParameter.DecodedCursor
+		predicate, err := keysetPredicate(qb.Sort, cursor)
+		if err != nil {
+			return nil, err
+		}
+		qb.Filter, qb.Keyset = withCondition(filter, nil), predicate
+		qb.Query = withCondition(filter, predicate)
+		if cursor.Type == paginator.PrevPage {
+			qb.Sort = reverseSort(qb.Sort)
+			qb.Reversed = true
+		}
+	case !stringops.IsBlank(paginationParameter.LastID):
+		objectID, e := primitive.ObjectIDFromHex(paginationParameter.LastID)
+		if e != nil {
+			return nil, ErrorUnableToParseLastID
+		}
+		operator := PaginationTypeQueryMapping[string(paginationParameter.Type)]
+		qb.Filter = withCondition(filter, nil)
+		qb.Keyset = bson.M{IDField: bson.M{string(operator): objectID}}
+		qb.Query = withCondition(filter, qb.Keyset)
+	default:
+		qb.Query = withCondition(filter, nil)
+		qb.Skip = getSkipCount(paginationParameter)
+	}
+
+	projection, err := paginationParameter.Projection()
+	if err != nil {
+		return nil, err
+	}
+	if projection != nil {
+		qb.Projection = projectionDocument(projection.With(qb.CursorFields()...))
 	}
 
 	return &qb, nil
 }
 
+// withCondition returns a copy of filter with condition added.
+// When filter already constrains one of the condition keys both are combined with $and,
+// the caller's filter is never modified.
+func withCondition(filter, condition bson.M) bson.M {
+	for k := range condition {
+		if _, ok := filter[k]; ok {
+			return bson.M{string(AndOp): bson.A{filter, condition}}
+		}
+	}
+	query := make(bson.M, len(filter)+len(condition))
+	for k, v := range filter {
+		query[k] = v
+	}
+	for k, v := range condition {
+		query[k] = v
+	}
+	return query
+}
+
+// CursorFields returns the field names, in order, a cursor for this query has to carry.
+func (f *QueryBuilder) CursorFields() []string {
+	fields := make([]string, 0, len(f.Sort))
+	for _, v := range f.Sort {
+		fields = append(fields, v.Name)
+	}
+	return fields
+}
+
+// withTieBreaker appends IDField to sort unless it is already part of it.
+// The tie-breaker follows the direction of the last sort field.
+func withTieBreaker(sort []SortType) []SortType {
+	direction := Desc
+	for _, v := range sort {
+		if v.Name == IDField {
+			return sort
+		}
+		direction = v.Direction
+	}
+	return append(sort, SortType{Name: IDField, Direction: direction})
+}
+
+// reverseSort returns sort with every direction inverted.
+func reverseSort(sort []SortType) []SortType {
+	reversed := make([]SortType, 0, len(sort))
+	for _, v := range sort {
+		reversed = append(reversed, SortType{Name: v.Name, Direction: -v.Direction})
+	}
+	return reversed
+}
+
+// keysetPredicate returns the lexicographic condition selecting rows after the cursor tuple:
+//
+//	(s0 > v0) OR (s0 = v0 AND s1 > v1) OR ...
+//
+// where the comparison of every field follows its direction and is flipped for previous pages.
+func keysetPredicate(sort []SortType, cursor *paginator.Cursor) (bson.M, error) {
+	if len(cursor.Keys) != len(sort) {
+		return nil, ErrCursorSortMismatch
+	}
+	for i, v := range sort {
+		if cursor.Keys[i] != v.Name {
+			return nil, ErrCursorSortMismatch
+		}
+	}
+
+	branches := make(bson.A, 0, len(sort))
+	for i, v := range sort {
+		branch := bson.M{}
+		for j := 0; j < i; j++ {
+			branch[sort[j].Name] = cursor.Values[j]
+		}
+		branch[v.Name] = bson.M{string(keysetOperator(v.Direction, cursor.Type)): cursor.Values[i]}
+		branches = append(branches, branch)
+	}
+	if len(branches) == 1 {
+		return branches[0].(bson.M), nil
+	}
+	return bson.M{string(OrOp): branches}, nil
+}
+
+// keysetOperator returns the operator selecting rows after the cursor value for given direction.
+func keysetOperator(direction SortDirection, t paginator.PaginationQueryType) Operator {
+	after := direction != Desc
+	if t == paginator.PrevPage {
+		after = !after
+	}
+	if after {
+		return GTOp
+	}
+	return LTOp
+}
+
 // getSortTypeFromOrderBy returns sort type from order by
 func getSortTypeFromOrderBy(orderBy paginator.OrderDirection) SortDirection {
 	switch orderBy {
@@ -166,5 +382,11 @@ func getSkipCount(paginationParameter paginator.PaginationQueryParam) int64 {
 	if paginationParameter.PageNo == 0 {
 		return 0
 	}
-	return (paginationParameter.PageNo - 1) * paginationParameter.PageSize
+	return (paginationParameter.PageNo - 1) * paginationParameter.EffectivePageSize()
+}
+
+// EstimateTotal returns the estimated document count of the collection from its metadata.
+// It ignores any filter and is meant for count free endpoints which are asked for an estimate.
+func EstimateTotal(ctx context.Context, coll *mongo.Collection) (int64, error) {
+	return coll.EstimatedDocumentCount(ctx)
 }
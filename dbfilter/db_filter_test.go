@@ -1,7 +1,7 @@
 package dbfilter
 
 import (
-	"net/http"
+	"errors"
 	"reflect"
 	"testing"
 
@@ -121,7 +121,7 @@ func TestGetDBFilterPaginationQuery(t *testing.T) {
 		filter              bson.M
 		paginationParameter paginator.PaginationQueryParam
 		want                *QueryBuilder
-		wantErr             *errorops.Error
+		wantErr             error
 	}{
 		{
 			name: "pagination query without any other parameter other than page size",
@@ -159,9 +159,11 @@ func TestGetDBFilterPaginationQuery(t *testing.T) {
 						"$lt": primitive.ObjectID{0x61, 0xf1, 0x26, 0xa1, 0xcf, 0x89, 0x7a, 0xa2, 0x61, 0x18, 0xd3, 0x44},
 					},
 				},
-				Sort:  []SortType{{Name: "tenant_id", Direction: Asc}},
-				Limit: 20,
-				Skip:  0,
+				Filter: bson.M{"tenant_id": "tenant1"},
+				Keyset: bson.M{"_id": bson.M{"$lt": primitive.ObjectID{0x61, 0xf1, 0x26, 0xa1, 0xcf, 0x89, 0x7a, 0xa2, 0x61, 0x18, 0xd3, 0x44}}},
+				Sort:   []SortType{{Name: "tenant_id", Direction: Asc}, {Name: "_id", Direction: Asc}},
+				Limit:  20,
+				Skip:   0,
 			},
 		},
 		{
@@ -179,11 +181,143 @@ func TestGetDBFilterPaginationQuery(t *testing.T) {
 				Query: bson.M{
 					"tenant_id": "tenant1",
 				},
-				Sort:  []SortType{{Name: "tenant_id", Direction: Desc}},
+				Sort:  []SortType{{Name: "tenant_id", Direction: Desc}, {Name: "_id", Direction: Desc}},
 				Limit: 20,
 				Skip:  20,
 			},
 		},
+		{
+			name: "next page cursor sorted by created_at desc",
+			filter: bson.M{
+				"tenant_id": "tenant1",
+			},
+			paginationParameter: paginator.PaginationQueryParam{
+				PageSize:      20,
+				Type:          paginator.NextPage,
+				SortBy:        []string{"created_at:desc"},
+				DecodedCursor: paginator.NewCursor(paginator.NextPage, []string{"created_at", "_id"}, int64(100), "id1"),
+			},
+			want: &QueryBuilder{
+				Query: bson.M{
+					"tenant_id": "tenant1",
+					"$or": bson.A{
+						bson.M{"created_at": bson.M{"$lt": int64(100)}},
+						bson.M{"created_at": int64(100), "_id": bson.M{"$lt": "id1"}},
+					},
+				},
+				Filter: bson.M{"tenant_id": "tenant1"},
+				Keyset: bson.M{"$or": bson.A{
+					bson.M{"created_at": bson.M{"$lt": int64(100)}},
+					bson.M{"created_at": int64(100), "_id": bson.M{"$lt": "id1"}},
+				}},
+				Sort:  []SortType{{Name: "created_at", Direction: Desc}, {Name: "_id", Direction: Desc}},
+				Limit: 20,
+			},
+		},
+		{
+			name: "prev page cursor with mixed directions reverses sort",
+			paginationParameter: paginator.PaginationQueryParam{
+				PageSize:      10,
+				Type:          paginator.PrevPage,
+				SortBy:        []string{"status:asc", "amount:desc"},
+				DecodedCursor: paginator.NewCursor(paginator.PrevPage, []string{"status", "amount", "_id"}, "active", int64(5), "id1"),
+			},
+			want: &QueryBuilder{
+				Query: bson.M{"$or": bson.A{
+					bson.M{"status": bson.M{"$lt": "active"}},
+					bson.M{"status": "active", "amount": bson.M{"$gt": int64(5)}},
+					bson.M{"status": "active", "amount": int64(5), "_id": bson.M{"$gt": "id1"}},
+				}},
+				Filter: bson.M{},
+				Keyset: bson.M{"$or": bson.A{
+					bson.M{"status": bson.M{"$lt": "active"}},
+					bson.M{"status": "active", "amount": bson.M{"$gt": int64(5)}},
+					bson.M{"status": "active", "amount": int64(5), "_id": bson.M{"$gt": "id1"}},
+				}},
+				Sort: []SortType{
+					{Name: "status", Direction: Desc},
+					{Name: "amount", Direction: Asc},
+					{Name: "_id", Direction: Asc},
+				},
+				Limit:    10,
+				Reversed: true,
+			},
+		},
+		{
+			name: "last id combined with caller's own _id condition",
+			filter: bson.M{
+				"_id": bson.M{"$ne": "excluded"},
+			},
+			paginationParameter: paginator.PaginationQueryParam{
+				PageSize: 20,
+				LastID:   "61f126a1cf897aa26118d344",
+				Type:     paginator.PrevPage,
+			},
+			want: &QueryBuilder{
+				Query: bson.M{"$and": bson.A{
+					bson.M{"_id": bson.M{"$ne": "excluded"}},
+					bson.M{"_id": bson.M{
+						"$gt": primitive.ObjectID{0x61, 0xf1, 0x26, 0xa1, 0xcf, 0x89, 0x7a, 0xa2, 0x61, 0x18, 0xd3, 0x44},
+					}},
+				}},
+				Filter: bson.M{"_id": bson.M{"$ne": "excluded"}},
+				Keyset: bson.M{"_id": bson.M{"$gt": primitive.ObjectID{0x61, 0xf1, 0x26, 0xa1, 0xcf, 0x89, 0x7a, 0xa2, 0x61, 0x18, 0xd3, 0x44}}},
+				Sort:   []SortType{{Name: "_id", Direction: Desc}},
+				Limit:  20,
+			},
+		},
+		{
+			name: "count free mode fetches one extra row",
+			paginationParameter: paginator.PaginationQueryParam{
+				PageNo:   3,
+				PageSize: 10,
+				Policy:   &paginator.PaginationPolicy{MinPageSize: 1, MaxPageSize: 50, CountFree: true},
+			},
+			want: &QueryBuilder{
+				Query: bson.M{},
+				Sort:  []SortType{{Name: "_id", Direction: Desc}},
+				Limit: 11,
+				Skip:  20,
+			},
+		},
+		{
+			name: "sort keys mapped through sort spec",
+			filter: bson.M{
+				"tenant_id": "tenant1",
+			},
+			paginationParameter: paginator.PaginationQueryParam{
+				PageNo:   1,
+				PageSize: 20,
+				SortBy:   []string{"created:asc"},
+				SortSpec: paginator.NewSortSpec().Allow("created", "created_at"),
+			},
+			want: &QueryBuilder{
+				Query: bson.M{
+					"tenant_id": "tenant1",
+				},
+				Sort:  []SortType{{Name: "created_at", Direction: Asc}, {Name: "_id", Direction: Asc}},
+				Limit: 20,
+			},
+		},
+		{
+			name: "sort key not declared in sort spec",
+			paginationParameter: paginator.PaginationQueryParam{
+				PageNo:   1,
+				PageSize: 20,
+				SortBy:   []string{"password:asc"},
+				SortSpec: paginator.NewSortSpec().Allow("created", "created_at"),
+			},
+			wantErr: &paginator.SortKeyError{Key: "password"},
+		},
+		{
+			name: "cursor issued for another sort",
+			paginationParameter: paginator.PaginationQueryParam{
+				PageSize:      10,
+				SortBy:        []string{"status:asc"},
+				DecodedCursor: paginator.NewCursor(paginator.NextPage, []string{"_id"}, "id1"),
+			},
+			wantErr: ErrCursorSortMismatch,
+		},
 		{
 			name: "pagination query with all pagination parameters, invalid sort format",
 			filter: bson.M{
@@ -195,40 +329,112 @@ func TestGetDBFilterPaginationQuery(t *testing.T) {
 				Type:     paginator.NextPage,
 				SortBy:   []string{"invalid_sort_format"},
 			},
-			wantErr: &errorops.Error{
-				Message: "bad request! fields validation error exist",
-				Fields: []errorops.Field{
-					{
-						Name:    "sort_by",
-						Message: []string{"invalid sort_by value: invalid_sort_format"},
-						Code:    "code-invalid",
-					},
-				},
-				Code:       "invalid-fields",
-				StatusCode: http.StatusBadRequest,
-			},
+			wantErr: &paginator.ValidationError{Fields: []paginator.FieldError{{
+				Field:   "sort_by",
+				Code:    paginator.CodeInvalid,
+				Message: "invalid sort_by value: invalid_sort_format",
+				Value:   "invalid_sort_format",
+			}}},
 		},
 		{
 			name: "invalid pagination parameter last id",
 			paginationParameter: paginator.PaginationQueryParam{
 				LastID: "61f126a1cf897aa26118d34461f126a1cf897aa26118d344",
 			},
-			wantErr: &errorops.Error{
-				Message: "unable to parse last_id",
-				Code:    "processing",
-				Err:     primitive.ErrInvalidHex,
-			},
+			wantErr: ErrorUnableToParseLastID,
 		},
 	}
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
+			filterCopy := bson.M{}
+			for k, v := range c.filter {
+				filterCopy[k] = v
+			}
 			got, gotErr := BuildQuery(c.filter, c.paginationParameter)
+			assert.Equal(t, len(filterCopy), len(c.filter), "caller's filter must not be modified")
 			if gotErr != nil || c.wantErr != nil {
-				c.wantErr.ID = gotErr.ID
-				assert.Equal(t, c.wantErr, gotErr)
+				var validationErr *paginator.ValidationError
+				if errors.As(c.wantErr, &validationErr) {
+					assert.Equal(t, c.wantErr, gotErr)
+				}
+				assert.EqualError(t, gotErr, c.wantErr.Error())
 			} else {
 				assert.Equal(t, c.want, got)
 			}
 		})
 	}
 }
+
+func TestBuildQuerySearch(t *testing.T) {
+	textPolicy := &paginator.PaginationPolicy{DefaultPageSize: 10, MinPageSize: 1, MaxPageSize: 50, Search: &paginator.SearchSpec{}}
+	prefixPolicy := &paginator.PaginationPolicy{DefaultPageSize: 10, MinPageSize: 1, MaxPageSize: 50, Search: &paginator.SearchSpec{Fields: []string{"code", "name"}, Mode: paginator.SearchPrefix}}
+
+	t.Run("text search ordered by relevance", func(t *testing.T) {
+		qb, err := BuildQuery(bson.M{"tenant_id": "t1"}, paginator.PaginationQueryParam{PageNo: 2, PageSize: 10, Search: "red shoes", Policy: textPolicy})
+		assert.NoError(t, err)
+		assert.True(t, qb.TextScore)
+		assert.Equal(t, bson.M{"tenant_id": "t1", "$text": bson.M{"$search": "red shoes"}}, qb.Query)
+		assert.Equal(t, int64(10), qb.Skip)
+
+		opts := options.Find().SetProjection(bson.M{"title": 1})
+		_, err = qb.ToMongo(opts)
+		assert.NoError(t, err)
+		assert.Equal(t, bson.D{{Key: "_score", Value: bson.M{"$meta": "textScore"}}, {Key: "_id", Value: Desc}}, opts.Sort)
+		assert.Equal(t, bson.M{"title": 1, "_score": bson.M{"$meta": "textScore"}}, opts.Projection)
+	})
+
+	t.Run("text score keeps the score field of the documents", func(t *testing.T) {
+		qb, err := BuildQuery(nil, paginator.PaginationQueryParam{PageNo: 1, PageSize: 10, Search: "red", Policy: textPolicy})
+		assert.NoError(t, err)
+
+		opts := options.Find().SetProjection(bson.M{"score": 1})
+		_, err = qb.ToMongo(opts)
+		assert.NoError(t, err)
+		assert.Equal(t, bson.M{"score": 1, "_score": bson.M{"$meta": "textScore"}}, opts.Projection)
+	})
+
+	t.Run("text search with sort_by keeps the sort", func(t *testing.T) {
+		qb, err := BuildQuery(nil, paginator.PaginationQueryParam{PageNo: 1, PageSize: 10, Search: "red", SortBy: []string{"name:asc"}, Policy: textPolicy})
+		assert.NoError(t, err)
+		assert.False(t, qb.TextScore)
+		assert.Equal(t, []SortType{{Name: "name", Direction: Asc}, {Name: "_id", Direction: Asc}}, qb.Sort)
+	})
+
+	t.Run("relevance search rejects cursors", func(t *testing.T) {
+		_, err := BuildQuery(nil, paginator.PaginationQueryParam{PageSize: 10, Search: "red", Policy: textPolicy, DecodedCursor: paginator.NewCursor(paginator.NextPage, []string{"_id"}, 1)})
+		assert.ErrorIs(t, err, paginator.ErrSearchCursor)
+	})
+
+	t.Run("prefix search is escaped and or-ed with existing $or", func(t *testing.T) {
+		filter := bson.M{"$or": bson.A{bson.M{"a": 1}, bson.M{"b": 1}}}
+		qb, err := BuildQuery(filter, paginator.PaginationQueryParam{PageNo: 1, PageSize: 10, Search: "a.b", Policy: prefixPolicy})
+		assert.NoError(t, err)
+		assert.Equal(t, bson.M{"$and": bson.A{filter, bson.M{"$or": bson.A{
+			bson.M{"code": bson.M{"$regex": `^a\.b`, "$options": "i"}},
+			bson.M{"name": bson.M{"$regex": `^a\.b`, "$options": "i"}},
+		}}}}, qb.Query)
+	})
+}
+
+func TestBuildQueryProjection(t *testing.T) {
+	policy := &paginator.PaginationPolicy{
+		DefaultPageSize: 10, MinPageSize: 1, MaxPageSize: 50,
+		Fields: paginator.NewFieldSpec().Allow("name", "name").Allow("notes", "internal_notes").Allow("created", "created_at"),
+	}
+
+	qb, err := BuildQuery(nil, paginator.PaginationQueryParam{PageNo: 1, PageSize: 10, Fields: []string{"name"}, SortBy: []string{"created_at:desc"}, Policy: policy})
+	assert.NoError(t, err)
+	assert.Equal(t, bson.D{{Key: "name", Value: 1}, {Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}, qb.Projection)
+
+	opts := options.Find()
+	_, err = qb.ToMongo(opts)
+	assert.NoError(t, err)
+	assert.Equal(t, qb.Projection, opts.Projection)
+
+	qb, err = BuildQuery(nil, paginator.PaginationQueryParam{PageNo: 1, PageSize: 10, Fields: []string{"-notes,-created"}, SortBy: []string{"created_at:desc"}, Policy: policy})
+	assert.NoError(t, err)
+	assert.Equal(t, bson.D{{Key: "internal_notes", Value: 0}}, qb.Projection)
+
+	_, err = BuildQuery(nil, paginator.PaginationQueryParam{PageNo: 1, PageSize: 10, Fields: []string{"password"}, Policy: policy})
+	assert.Error(t, err)
+}
@@ -0,0 +1,170 @@
+package dbfilter
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/a01k-io/modules/paginator"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// PageResult is a typed page returned by FindPage
+type PageResult[T any] struct {
+	paginator.Page[T]
+	// Took is the time spent running the find and count queries
+	Took time.Duration `json:"-"`
+}
+
+// pageRow keeps a decoded document together with its raw form for cursor extraction.
+type pageRow[T any] struct {
+	item T
+	raw  bson.Raw
+}
+
+// FindPage runs the paginated find for filter and params and decodes the documents into T.
+// The total is counted concurrently with the find, in CountFree mode it is skipped, or
+// estimated when params.EstimateTotal is set. One extra document is fetched to set
+// HasNext and HasPrev, and cursors are issued from the first and last document of the page
+// unless the page is ordered by text search relevance. Cursors are signed with
+// params.EffectiveSigner, the signer the request cursor was verified with.
+// opts are merged into the find options before sort, skip and limit are applied.
+func FindPage[T any](ctx context.Context, coll *mongo.Collection, filter bson.M, params paginator.PaginationQueryParam, opts ...*options.FindOptions) (*PageResult[T], error) {
+	start := time.Now()
+
+	qb, err := buildPageQuery(filter, params)
+	if err != nil {
+		return nil, err
+	}
+	findOpts := options.MergeFindOptions(opts...)
+	query, err := qb.ToMongo(findOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		total    int64
+		counted  bool
+		countErr error
+	)
+	countFree := params.IsCountFree()
+	countFilter := withCondition(filter, searchCondition(params))
+	if !countFree || params.EstimateTotal {
+		counted = true
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if countFree {
+				total, countErr = EstimateTotal(ctx, coll)
+				return
+			}
+			total, countErr = coll.CountDocuments(ctx, countFilter)
+		}()
+	}
+
+	rows, findErr := findRows[T](ctx, coll, query, findOpts)
+	if findErr != nil {
+		cancel()
+	}
+	wg.Wait()
+	if findErr != nil {
+		return nil, findErr
+	}
+	if countErr != nil {
+		return nil, countErr
+	}
+
+	page, err := newRowsPage(rows, params, qb)
+	if err != nil {
+		return nil, err
+	}
+	if counted {
+		page.SetEstimatedTotal(total)
+		page.Pagination.TotalEstimated = countFree
+	}
+
+	return &PageResult[T]{Page: page, Took: time.Since(start)}, nil
+}
+
+// newRowsPage trims the extra row fetched by FindPage setting HasNext and HasPrev, reverses
+// previous keyset pages into display order and issues the cursors of the page.
+func newRowsPage[T any](rows []pageRow[T], params paginator.PaginationQueryParam, qb *QueryBuilder) (paginator.Page[T], error) {
+	rowPage := paginator.NewHasMorePage(rows, params)
+	items := make([]T, 0, len(rowPage.Items))
+	for _, r := range rowPage.Items {
+		items = append(items, r.item)
+	}
+	page := paginator.Page[T]{
+		Items:      items,
+		Pagination: rowPage.Pagination,
+		HasNext:    rowPage.HasNext,
+		HasPrev:    rowPage.HasPrev,
+	}
+
+	if len(rowPage.Items) > 0 && !qb.TextScore {
+		fields := qb.CursorFields()
+		var next, prev *paginator.Cursor
+		if page.HasNext {
+			next = paginator.NewCursor(paginator.NextPage, fields, cursorValues(rowPage.Items[len(rowPage.Items)-1].raw, fields)...)
+		}
+		if page.HasPrev {
+			prev = paginator.NewCursor(paginator.PrevPage, fields, cursorValues(rowPage.Items[0].raw, fields)...)
+		}
+		if err := page.SetSignedCursors(params.EffectiveSigner(), next, prev); err != nil {
+			return page, err
+		}
+	}
+	return page, nil
+}
+
+// buildPageQuery returns the query of FindPage, it fetches one document more than the page size.
+func buildPageQuery(filter bson.M, params paginator.PaginationQueryParam) (*QueryBuilder, error) {
+	qb, err := BuildQuery(filter, params)
+	if err != nil {
+		return nil, err
+	}
+	if pageSize := params.EffectivePageSize(); pageSize > 0 {
+		qb.Limit = pageSize + 1
+	}
+	return qb, nil
+}
+
+// findRows runs the find and decodes every document into T keeping its raw form.
+func findRows[T any](ctx context.Context, coll *mongo.Collection, query bson.M, opts *options.FindOptions) ([]pageRow[T], error) {
+	cur, err := coll.Find(ctx, query, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	rows := make([]pageRow[T], 0)
+	for cur.Next(ctx) {
+		var row pageRow[T]
+		if err := cur.Decode(&row.item); err != nil {
+			return nil, err
+		}
+		row.raw = append(bson.Raw(nil), cur.Current...)
+		rows = append(rows, row)
+	}
+	return rows, cur.Err()
+}
+
+// cursorValues returns the values of fields, dotted paths included, from raw. Missing fields yield nil.
+func cursorValues(raw bson.Raw, fields []string) []interface{} {
+	values := make([]interface{}, 0, len(fields))
+	for _, f := range fields {
+		var v interface{}
+		if rv, err := raw.LookupErr(strings.Split(f, ".")...); err == nil {
+			_ = rv.Unmarshal(&v)
+		}
+		values = append(values, v)
+	}
+	return values
+}
@@ -0,0 +1,166 @@
+package dbfilter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/a01k-io/modules/paginator"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCursorValues(t *testing.T) {
+	id := primitive.NewObjectID()
+	createdAt := time.Date(2024, 7, 1, 10, 0, 0, 0, time.UTC)
+	raw, err := bson.Marshal(bson.D{
+		{Key: "_id", Value: id},
+		{Key: "created_at", Value: createdAt},
+		{Key: "customer", Value: bson.D{{Key: "name", Value: "bob"}}},
+	})
+	assert.NoError(t, err)
+
+	got := cursorValues(raw, []string{"created_at", "customer.name", "missing", "_id"})
+	assert.Equal(t, []interface{}{primitive.NewDateTimeFromTime(createdAt), "bob", nil, id}, got)
+}
+
+type pageItem struct {
+	ID     int32  `bson:"_id"`
+	Status string `bson:"status"`
+}
+
+// newPageRows returns the rows FindPage decodes from documents with the given ids.
+func newPageRows(t *testing.T, ids ...int32) []pageRow[pageItem] {
+	rows := make([]pageRow[pageItem], 0, len(ids))
+	for _, id := range ids {
+		item := pageItem{ID: id, Status: "active"}
+		raw, err := bson.Marshal(item)
+		assert.NoError(t, err)
+		rows = append(rows, pageRow[pageItem]{item: item, raw: raw})
+	}
+	return rows
+}
+
+// decodeCursor returns the cursor of token, nil when token is empty.
+func decodeCursor(t *testing.T, token string) *paginator.Cursor {
+	if token == "" {
+		return nil
+	}
+	cursor, err := paginator.DefaultCursorSigner.Decode(token)
+	assert.NoError(t, err)
+	return cursor
+}
+
+func TestBuildPageQuery(t *testing.T) {
+	params := paginator.PaginationQueryParam{PageNo: 1, PageSize: 3}
+	qb, err := buildPageQuery(bson.M{}, params)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), qb.Limit, "one document more than the page size")
+	assert.False(t, qb.Reversed)
+
+	params.DecodedCursor = paginator.NewCursor(paginator.PrevPage, []string{"_id"}, int32(5))
+	params.Type = paginator.PrevPage
+	qb, err = buildPageQuery(bson.M{}, params)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), qb.Limit)
+	assert.True(t, qb.Reversed)
+	assert.Equal(t, []SortType{{Name: "_id", Direction: Asc}}, qb.Sort)
+
+	params = paginator.PaginationQueryParam{PageNo: 1}
+	qb, err = buildPageQuery(bson.M{}, params)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), qb.Limit, "no page size means no limit")
+}
+
+func TestNewRowsPage(t *testing.T) {
+	keys := []string{"_id"}
+	nextCursor := paginator.NewCursor(paginator.NextPage, keys, int32(10))
+	prevCursor := paginator.NewCursor(paginator.PrevPage, keys, int32(1))
+
+	cases := []struct {
+		name      string
+		params    paginator.PaginationQueryParam
+		textScore bool
+		rows      []int32
+		wantIDs   []int32
+		wantNext  bool
+		wantPrev  bool
+		next      *paginator.Cursor
+		prev      *paginator.Cursor
+	}{
+		{
+			name:     "first page with more documents",
+			params:   paginator.PaginationQueryParam{PageNo: 1, PageSize: 3},
+			rows:     []int32{9, 8, 7, 6},
+			wantIDs:  []int32{9, 8, 7},
+			wantNext: true,
+			next:     paginator.NewCursor(paginator.NextPage, keys, int64(7)),
+		},
+		{
+			name:    "last offset page",
+			params:  paginator.PaginationQueryParam{PageNo: 1, PageSize: 3},
+			rows:    []int32{9, 8},
+			wantIDs: []int32{9, 8},
+		},
+		{
+			name:     "next keyset page without more documents",
+			params:   paginator.PaginationQueryParam{PageSize: 3, Type: paginator.NextPage, DecodedCursor: nextCursor},
+			rows:     []int32{6, 5, 4},
+			wantIDs:  []int32{6, 5, 4},
+			wantPrev: true,
+			prev:     paginator.NewCursor(paginator.PrevPage, keys, int64(6)),
+		},
+		{
+			name:     "previous keyset page is reversed into display order",
+			params:   paginator.PaginationQueryParam{PageSize: 3, Type: paginator.PrevPage, DecodedCursor: prevCursor},
+			rows:     []int32{2, 3, 4, 5},
+			wantIDs:  []int32{4, 3, 2},
+			wantNext: true,
+			wantPrev: true,
+			next:     paginator.NewCursor(paginator.NextPage, keys, int64(2)),
+			prev:     paginator.NewCursor(paginator.PrevPage, keys, int64(4)),
+		},
+		{
+			name:      "relevance pages issue no cursors",
+			params:    paginator.PaginationQueryParam{PageNo: 2, PageSize: 3},
+			textScore: true,
+			rows:      []int32{9, 8, 7, 6},
+			wantIDs:   []int32{9, 8, 7},
+			wantNext:  true,
+			wantPrev:  true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			qb := &QueryBuilder{Sort: []SortType{{Name: "_id", Direction: Desc}}, TextScore: c.textScore}
+			page, err := newRowsPage(newPageRows(t, c.rows...), c.params, qb)
+			assert.NoError(t, err)
+
+			ids := make([]int32, 0, len(page.Items))
+			for _, item := range page.Items {
+				ids = append(ids, item.ID)
+			}
+			assert.Equal(t, c.wantIDs, ids)
+			assert.Equal(t, c.wantNext, page.HasNext)
+			assert.Equal(t, c.wantPrev, page.HasPrev)
+			assert.Equal(t, c.next, decodeCursor(t, page.NextCursor))
+			assert.Equal(t, c.prev, decodeCursor(t, page.PrevCursor))
+		})
+	}
+}
+
+func TestNewRowsPageSigner(t *testing.T) {
+	signer := paginator.NewCursorSigner([]byte("shared-secret"), 0)
+	params := paginator.PaginationQueryParam{PageNo: 1, PageSize: 2}
+	assert.NoError(t, params.DecodeCursor(signer))
+
+	qb := &QueryBuilder{Sort: []SortType{{Name: "_id", Direction: Desc}}}
+	page, err := newRowsPage(newPageRows(t, 9, 8, 7), params, qb)
+	assert.NoError(t, err)
+
+	cursor, err := signer.Decode(page.NextCursor)
+	assert.NoError(t, err)
+	assert.Equal(t, paginator.NewCursor(paginator.NextPage, []string{"_id"}, int64(8)), cursor)
+	_, err = paginator.DefaultCursorSigner.Decode(page.NextCursor)
+	assert.ErrorIs(t, err, paginator.ErrCursorTampered)
+}
@@ -0,0 +1,229 @@
+package dbfilter
+
+import (
+	"context"
+	"sort"
+
+	"github.com/a01k-io/modules/paginator"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	// FacetData is the $facet output holding the page documents.
+	FacetData = "data"
+	// FacetTotal is the $facet output holding the total count.
+	FacetTotal = "total"
+)
+
+// Pipeline is an immutable aggregation pipeline builder, every method returns a new Pipeline.
+// Stages are bson.D so their order, and the key order of $sort, is kept.
+//
+//	p := qb.Pipeline().
+//		Lookup("customers", "customer_id", "_id", "customer").
+//		Unwind("$customer", true).
+//		Paginate(qb)
+type Pipeline struct {
+	stages mongo.Pipeline
+}
+
+// NewPipeline returns an empty Pipeline.
+func NewPipeline() Pipeline {
+	return Pipeline{}
+}
+
+// Pipeline returns a pipeline starting with $match of the query. Keyset pages are sorted
+// right after the $match, so both the Keyset condition and the sort can use an index.
+func (f *QueryBuilder) Pipeline() Pipeline {
+	p := NewPipeline().Match(f.Query)
+	if f.Keyset != nil && len(f.Sort) != 0 {
+		p = p.Stage(bson.D{{Key: "$sort", Value: f.mongoSort()}})
+	}
+	return p
+}
+
+// Stage appends a raw stage.
+func (p Pipeline) Stage(stage bson.D) Pipeline {
+	stages := make(mongo.Pipeline, len(p.stages), len(p.stages)+1)
+	copy(stages, p.stages)
+	return Pipeline{stages: append(stages, stage)}
+}
+
+// Match appends a $match stage, filter may be bson.M, bson.D or Filter.
+func (p Pipeline) Match(filter interface{}) Pipeline {
+	if f, ok := filter.(Filter); ok {
+		filter = f.D()
+	}
+	return p.Stage(bson.D{{Key: "$match", Value: filter}})
+}
+
+// Sort appends a $sort stage keeping the order of sort.
+func (p Pipeline) Sort(sortTypes []SortType) Pipeline {
+	if len(sortTypes) == 0 {
+		return p
+	}
+	return p.Stage(bson.D{{Key: "$sort", Value: sortDocument(sortTypes)}})
+}
+
+// Skip appends a $skip stage.
+func (p Pipeline) Skip(n int64) Pipeline {
+	return p.Stage(bson.D{{Key: "$skip", Value: n}})
+}
+
+// Limit appends a $limit stage.
+func (p Pipeline) Limit(n int64) Pipeline {
+	return p.Stage(bson.D{{Key: "$limit", Value: n}})
+}
+
+// Lookup appends a $lookup stage joining the documents of from where foreignField equals localField.
+func (p Pipeline) Lookup(from, localField, foreignField, as string) Pipeline {
+	return p.Stage(bson.D{{Key: "$lookup", Value: bson.D{
+		{Key: "from", Value: from},
+		{Key: "localField", Value: localField},
+		{Key: "foreignField", Value: foreignField},
+		{Key: "as", Value: as},
+	}}})
+}
+
+// LookupPipeline appends a $lookup stage joining the documents of from selected by sub,
+// let defines the variables of the local document sub can access.
+func (p Pipeline) LookupPipeline(from string, let bson.D, sub Pipeline, as string) Pipeline {
+	lookup := bson.D{{Key: "from", Value: from}}
+	if len(let) != 0 {
+		lookup = append(lookup, bson.E{Key: "let", Value: let})
+	}
+	lookup = append(lookup, bson.E{Key: "pipeline", Value: sub.Build()}, bson.E{Key: "as", Value: as})
+	return p.Stage(bson.D{{Key: "$lookup", Value: lookup}})
+}
+
+// Project appends a $project stage.
+func (p Pipeline) Project(fields bson.D) Pipeline {
+	return p.Stage(bson.D{{Key: "$project", Value: fields}})
+}
+
+// Unwind appends an $unwind stage of path, e.g. "$items".
+// preserveNullAndEmpty keeps documents where path is missing or empty.
+func (p Pipeline) Unwind(path string, preserveNullAndEmpty bool) Pipeline {
+	return p.Stage(bson.D{{Key: "$unwind", Value: bson.D{
+		{Key: "path", Value: path},
+		{Key: "preserveNullAndEmptyArrays", Value: preserveNullAndEmpty},
+	}}})
+}
+
+// Group appends a $group stage with id as _id and the accumulators in order.
+func (p Pipeline) Group(id interface{}, accumulators bson.D) Pipeline {
+	group := append(bson.D{{Key: "_id", Value: id}}, accumulators...)
+	return p.Stage(bson.D{{Key: "$group", Value: group}})
+}
+
+// Facet appends a $facet stage running every sub pipeline on the same input.
+func (p Pipeline) Facet(facets map[string]Pipeline) Pipeline {
+	names := make([]string, 0, len(facets))
+	for name := range facets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	facet := make(bson.D, 0, len(facets))
+	for _, name := range names {
+		facet = append(facet, bson.E{Key: name, Value: facets[name].Build()})
+	}
+	return p.Stage(bson.D{{Key: "$facet", Value: facet}})
+}
+
+// Paginate appends a $facet returning the page of qb under FacetData and the total count under FacetTotal
+// in one round trip, text search relevance ordering of qb is kept. stages run in the data facet after $limit, e.g. a $lookup of the page documents only.
+// p should start with qb.Pipeline(). Keyset pages are limited before the $facet, which holds no FacetTotal then,
+// since the total must also count the rows before the boundary row, AggregatePage counts it separately.
+// Decode the result with FacetResult, or run it with AggregatePage.
+func (p Pipeline) Paginate(qb *QueryBuilder, stages ...bson.D) Pipeline {
+	data := NewPipeline()
+	if qb.Keyset != nil {
+		if qb.Limit > 0 {
+			p = p.Limit(qb.Limit)
+		}
+	} else {
+		if len(qb.Sort) != 0 || qb.TextScore {
+			data = data.Stage(bson.D{{Key: "$sort", Value: qb.mongoSort()}})
+		}
+		if qb.Skip > 0 {
+			data = data.Skip(qb.Skip)
+		}
+		if qb.Limit > 0 {
+			data = data.Limit(qb.Limit)
+		}
+	}
+	if qb.Projection != nil {
+		data = data.Project(qb.Projection)
+	}
+	if qb.TextScore {
+		data = data.Stage(bson.D{{Key: "$addFields", Value: bson.M{paginator.SearchScoreField: textScore}}})
+	}
+	for _, s := range stages {
+		data = data.Stage(s)
+	}
+	facets := map[string]Pipeline{FacetData: data}
+	if qb.Keyset == nil {
+		facets[FacetTotal] = NewPipeline().Stage(bson.D{{Key: "$count", Value: "count"}})
+	}
+	return p.Facet(facets)
+}
+
+// Build returns the stages to pass to Aggregate.
+func (p Pipeline) Build() mongo.Pipeline {
+	stages := make(mongo.Pipeline, len(p.stages))
+	copy(stages, p.stages)
+	return stages
+}
+
+// FacetResult is the document returned by a pipeline ending with Paginate
+type FacetResult[T any] struct {
+	Data  []T `bson:"data"`
+	Total []struct {
+		Count int64 `bson:"count"`
+	} `bson:"total"`
+}
+
+// TotalCount returns the total count, zero when nothing matched.
+func (r FacetResult[T]) TotalCount() int64 {
+	if len(r.Total) == 0 {
+		return 0
+	}
+	return r.Total[0].Count
+}
+
+// AggregatePage runs a pipeline ending with Paginate of qb and returns the page documents and the total count.
+// Previous keyset pages, see QueryBuilder.Reversed, are reversed into display order. The total of keyset pages
+// is counted by a separate query of qb.Filter, so stages between qb.Pipeline() and Paginate should not
+// drop or multiply documents.
+func AggregatePage[T any](ctx context.Context, coll *mongo.Collection, qb *QueryBuilder, p Pipeline) ([]T, int64, error) {
+	cur, err := coll.Aggregate(ctx, p.Build())
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cur.Close(ctx)
+
+	var result FacetResult[T]
+	if cur.Next(ctx) {
+		if err := cur.Decode(&result); err != nil {
+			return nil, 0, err
+		}
+	}
+	if err := cur.Err(); err != nil {
+		return nil, 0, err
+	}
+	if result.Data == nil {
+		result.Data = make([]T, 0)
+	}
+	if qb.Reversed {
+		paginator.Reverse(result.Data)
+	}
+	if qb.Keyset != nil {
+		total, err := coll.CountDocuments(ctx, qb.Filter)
+		if err != nil {
+			return nil, 0, err
+		}
+		return result.Data, total, nil
+	}
+	return result.Data, result.TotalCount(), nil
+}
@@ -0,0 +1,123 @@
+package dbfilter
+
+import (
+	"testing"
+
+	"github.com/a01k-io/modules/paginator"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestPipeline(t *testing.T) {
+	qb := &QueryBuilder{
+		Query: bson.M{"status": "active"},
+		Sort:  []SortType{{Name: "created_at", Direction: Desc}, {Name: IDField, Direction: Desc}},
+		Limit: 10,
+		Skip:  20,
+	}
+
+	base := qb.Pipeline().
+		Lookup("customers", "customer_id", "_id", "customer").
+		Unwind("$customer", true)
+	p := base.Paginate(qb, bson.D{{Key: "$project", Value: bson.D{{Key: "secret", Value: 0}}}})
+
+	want := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"status": "active"}}},
+		{{Key: "$lookup", Value: bson.D{
+			{Key: "from", Value: "customers"},
+			{Key: "localField", Value: "customer_id"},
+			{Key: "foreignField", Value: "_id"},
+			{Key: "as", Value: "customer"},
+		}}},
+		{{Key: "$unwind", Value: bson.D{
+			{Key: "path", Value: "$customer"},
+			{Key: "preserveNullAndEmptyArrays", Value: true},
+		}}},
+		{{Key: "$facet", Value: bson.D{
+			{Key: FacetData, Value: mongo.Pipeline{
+				{{Key: "$sort", Value: bson.D{{Key: "created_at", Value: Desc}, {Key: IDField, Value: Desc}}}},
+				{{Key: "$skip", Value: int64(20)}},
+				{{Key: "$limit", Value: int64(10)}},
+				{{Key: "$project", Value: bson.D{{Key: "secret", Value: 0}}}},
+			}},
+			{Key: FacetTotal, Value: mongo.Pipeline{
+				{{Key: "$count", Value: "count"}},
+			}},
+		}}},
+	}
+	assert.Equal(t, want, p.Build())
+	assert.Len(t, base.Build(), 3, "Paginate must not modify the receiver")
+}
+
+func TestPipelineKeysetPage(t *testing.T) {
+	qb, err := BuildQuery(bson.M{"status": "active"}, paginator.PaginationQueryParam{
+		PageSize:      10,
+		Type:          paginator.NextPage,
+		DecodedCursor: paginator.NewCursor(paginator.NextPage, []string{IDField}, "id1"),
+	})
+	assert.NoError(t, err)
+
+	want := mongo.Pipeline{
+		{{Key: "$match", Value: qb.Query}},
+		{{Key: "$sort", Value: bson.D{{Key: IDField, Value: Desc}}}},
+		{{Key: "$lookup", Value: bson.D{
+			{Key: "from", Value: "customers"},
+			{Key: "localField", Value: "customer_id"},
+			{Key: "foreignField", Value: "_id"},
+			{Key: "as", Value: "customer"},
+		}}},
+		{{Key: "$limit", Value: int64(10)}},
+		{{Key: "$facet", Value: bson.D{
+			{Key: FacetData, Value: mongo.Pipeline{
+				{{Key: "$project", Value: bson.D{{Key: "secret", Value: 0}}}},
+			}},
+		}}},
+	}
+	p := qb.Pipeline().
+		Lookup("customers", "customer_id", "_id", "customer").
+		Paginate(qb, bson.D{{Key: "$project", Value: bson.D{{Key: "secret", Value: 0}}}})
+	assert.Equal(t, want, p.Build(), "the keyset match, sort and limit run before the facet")
+	assert.Equal(t, bson.M{"status": "active"}, qb.Filter, "the total is counted on the filter")
+}
+
+func TestPipelineStages(t *testing.T) {
+	sub := NewPipeline().Match(NewFilter().Eq("active", true))
+	p := NewPipeline().
+		LookupPipeline("items", bson.D{{Key: "order", Value: "$_id"}}, sub, "items").
+		Group("$status", bson.D{{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}}}).
+		Sort(nil)
+
+	want := mongo.Pipeline{
+		{{Key: "$lookup", Value: bson.D{
+			{Key: "from", Value: "items"},
+			{Key: "let", Value: bson.D{{Key: "order", Value: "$_id"}}},
+			{Key: "pipeline", Value: mongo.Pipeline{
+				{{Key: "$match", Value: bson.D{{Key: "active", Value: bson.D{{Key: "$eq", Value: true}}}}}},
+			}},
+			{Key: "as", Value: "items"},
+		}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$status"},
+			{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+		}}},
+	}
+	assert.Equal(t, want, p.Build())
+}
+
+func TestFacetResultTotalCount(t *testing.T) {
+	raw, err := bson.Marshal(bson.D{
+		{Key: FacetData, Value: bson.A{bson.D{{Key: "name", Value: "a"}}}},
+		{Key: FacetTotal, Value: bson.A{bson.D{{Key: "count", Value: int32(7)}}}},
+	})
+	assert.NoError(t, err)
+
+	var result FacetResult[struct {
+		Name string `bson:"name"`
+	}]
+	assert.NoError(t, bson.Unmarshal(raw, &result))
+	assert.Equal(t, int64(7), result.TotalCount())
+	assert.Equal(t, "a", result.Data[0].Name)
+
+	assert.Equal(t, int64(0), FacetResult[bson.M]{}.TotalCount())
+}
@@ -0,0 +1,68 @@
+package dbfilter
+
+import (
+	"regexp"
+
+	"github.com/a01k-io/modules/paginator"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// textScore is the $meta expression of the text search relevance.
+var textScore = bson.M{"$meta": "textScore"}
+
+// searchCondition returns the condition matching the search term of params, nil without search.
+// SearchText uses the text index of the collection, SearchPrefix matches any of the spec fields
+// starting with the escaped term, case-insensitive.
+func searchCondition(params paginator.PaginationQueryParam) bson.M {
+	spec := params.SearchSpec()
+	if spec == nil {
+		return nil
+	}
+	term := params.SearchTerm()
+	if spec.Mode == paginator.SearchText {
+		return bson.M{string(TextOp): bson.M{"$search": term}}
+	}
+	if len(spec.Fields) == 0 {
+		return nil
+	}
+
+	conditions := make(bson.A, 0, len(spec.Fields))
+	for _, f := range spec.Fields {
+		conditions = append(conditions, bson.M{f: bson.M{
+			string(RegexOp):        "^" + regexp.QuoteMeta(term),
+			string(RegexOptionsOp): "i",
+		}})
+	}
+	if len(conditions) == 1 {
+		return conditions[0].(bson.M)
+	}
+	return bson.M{string(OrOp): conditions}
+}
+
+// mongoSort returns the sort document of the query, led by the text score when relevance sorted.
+func (f *QueryBuilder) mongoSort() bson.D {
+	sort := sortDocument(f.Sort)
+	if !f.TextScore {
+		return sort
+	}
+	return append(bson.D{{Key: paginator.SearchScoreField, Value: textScore}}, sort...)
+}
+
+// withTextScore returns a copy of projection which also returns the text score.
+// A projection other than nil, bson.M or bson.D is returned as is.
+func withTextScore(projection interface{}) interface{} {
+	switch p := projection.(type) {
+	case nil:
+		return bson.M{paginator.SearchScoreField: textScore}
+	case bson.M:
+		m := make(bson.M, len(p)+1)
+		for k, v := range p {
+			m[k] = v
+		}
+		m[paginator.SearchScoreField] = textScore
+		return m
+	case bson.D:
+		return append(append(bson.D(nil), p...), bson.E{Key: paginator.SearchScoreField, Value: textScore})
+	}
+	return projection
+}
@@ -0,0 +1,169 @@
+package paginator
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	// ErrCursorMalformed is returned when a cursor token cannot be decoded.
+	ErrCursorMalformed = errors.New("malformed cursor")
+	// ErrCursorTampered is returned when the cursor signature does not match its payload.
+	ErrCursorTampered = errors.New("cursor signature mismatch")
+	// ErrCursorExpired is returned when the cursor is older than the signer ttl.
+	ErrCursorExpired = errors.New("cursor expired")
+
+	// DefaultCursorSigner is used by NewPaginationQueryParams to verify cursor tokens
+	// and by PaginatedResponse.SetCursors to issue them when no other signer is configured.
+	// It is seeded with a random key, services running more than one replica
+	// must replace it with a signer built from a shared secret.
+	DefaultCursorSigner = NewCursorSigner(randomCursorKey(), 0)
+)
+
+// CursorError is returned when a cursor token is rejected.
+type CursorError struct {
+	Reason error
+}
+
+func (e *CursorError) Error() string {
+	return "invalid cursor: " + e.Reason.Error()
+}
+
+// Unwrap returns the reason the cursor was rejected.
+func (e *CursorError) Unwrap() error {
+	return e.Reason
+}
+
+// Cursor is the decoded form of an opaque pagination token.
+// It carries the sort-key tuple of the boundary row and the direction to page in.
+type Cursor struct {
+	// Keys holds the sort field names, in order, the Values belong to.
+	Keys []string `bson:"k"`
+	// Values holds the sort field values of the boundary row.
+	Values []interface{} `bson:"v"`
+	// Type is the direction to page in relative to the boundary row.
+	Type PaginationQueryType `bson:"t"`
+	// ExpiresAt is the unix time after which the cursor is rejected, zero means never.
+	ExpiresAt int64 `bson:"e,omitempty"`
+}
+
+// NewCursor returns cursor for given direction and sort-key tuple.
+func NewCursor(t PaginationQueryType, keys []string, values ...interface{}) *Cursor {
+	return &Cursor{Keys: keys, Values: values, Type: t}
+}
+
+// Value returns the value stored for key and whether it was found.
+func (c *Cursor) Value(key string) (interface{}, bool) {
+	for i, k := range c.Keys {
+		if k == key && i < len(c.Values) {
+			return c.Values[i], true
+		}
+	}
+	return nil, false
+}
+
+// CursorSigner encodes cursors into signed base64url tokens and verifies them.
+type CursorSigner struct {
+	key []byte
+	ttl time.Duration
+	now func() time.Time
+}
+
+// NewCursorSigner returns a signer using key for the HMAC-SHA256 signature.
+// Tokens older than ttl are rejected, ttl of zero disables expiry.
+func NewCursorSigner(key []byte, ttl time.Duration) *CursorSigner {
+	return &CursorSigner{key: key, ttl: ttl, now: time.Now}
+}
+
+// Encode serializes c and returns the signed token.
+func (s *CursorSigner) Encode(c Cursor) (string, error) {
+	if !c.Type.Valid() {
+		return "", errors.Errorf("invalid cursor type: %v", c.Type)
+	}
+	if len(c.Keys) != len(c.Values) {
+		return "", errors.New("cursor keys and values length mismatch")
+	}
+	if s.ttl > 0 {
+		c.ExpiresAt = s.now().Add(s.ttl).Unix()
+	}
+	payload, err := bson.Marshal(c)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to encode cursor")
+	}
+	return base64.RawURLEncoding.EncodeToString(append(payload, s.sign(payload)...)), nil
+}
+
+// Decode verifies token and returns the cursor it carries.
+// Rejected tokens are reported as *CursorError.
+func (s *CursorSigner) Decode(token string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(raw) <= sha256.Size {
+		return nil, &CursorError{Reason: ErrCursorMalformed}
+	}
+	payload, signature := raw[:len(raw)-sha256.Size], raw[len(raw)-sha256.Size:]
+	if !hmac.Equal(signature, s.sign(payload)) {
+		return nil, &CursorError{Reason: ErrCursorTampered}
+	}
+
+	var c Cursor
+	if err := bson.Unmarshal(payload, &c); err != nil {
+		return nil, &CursorError{Reason: ErrCursorMalformed}
+	}
+	if !c.Type.Valid() || len(c.Keys) != len(c.Values) {
+		return nil, &CursorError{Reason: ErrCursorMalformed}
+	}
+	if c.ExpiresAt != 0 && s.now().Unix() > c.ExpiresAt {
+		return nil, &CursorError{Reason: ErrCursorExpired}
+	}
+	for i := range c.Values {
+		c.Values[i] = normalizeCursorValue(c.Values[i])
+	}
+	return &c, nil
+}
+
+func (s *CursorSigner) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// normalizeCursorValue converts bson decoded values back to the go types they were encoded from.
+func normalizeCursorValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case primitive.DateTime:
+		return t.Time().UTC()
+	case int32:
+		return int64(t)
+	}
+	return v
+}
+
+func randomCursorKey() []byte {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(err)
+	}
+	return key
+}
+
+// Reversed reports whether the params select a previous keyset page, per the type of DecodedCursor,
+// such pages are fetched in inverted sort order.
+func (p *PaginationQueryParam) Reversed() bool {
+	return p.DecodedCursor != nil && p.DecodedCursor.Type == PrevPage
+}
+
+// Reverse reverses items in place.
+// Keyset queries for a previous page are run in inverted sort order, their results
+// have to be reversed to come back in display order.
+func Reverse[T any](items []T) {
+	for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+		items[i], items[j] = items[j], items[i]
+	}
+}
@@ -0,0 +1,93 @@
+package paginator
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCursorSignerRoundTrip(t *testing.T) {
+	signer := NewCursorSigner([]byte("secret"), time.Hour)
+	createdAt := time.Date(2024, 7, 1, 10, 30, 0, 0, time.UTC)
+	objectID := primitive.NewObjectID()
+
+	token, err := signer.Encode(*NewCursor(NextPage, []string{"created_at", "amount", "_id"}, createdAt, 42, objectID))
+	assert.NoError(t, err)
+
+	got, err := signer.Decode(token)
+	assert.NoError(t, err)
+	assert.Equal(t, NextPage, got.Type)
+	assert.Equal(t, []string{"created_at", "amount", "_id"}, got.Keys)
+	assert.Equal(t, []interface{}{createdAt, int64(42), objectID}, got.Values)
+
+	v, ok := got.Value("_id")
+	assert.True(t, ok)
+	assert.Equal(t, objectID, v)
+}
+
+func TestCursorSignerRejects(t *testing.T) {
+	signer := NewCursorSigner([]byte("secret"), time.Minute)
+	token, err := signer.Encode(*NewCursor(PrevPage, []string{"_id"}, "abc"))
+	assert.NoError(t, err)
+
+	raw := []byte(token)
+	if raw[5] == 'A' {
+		raw[5] = 'B'
+	} else {
+		raw[5] = 'A'
+	}
+	expired := NewCursorSigner([]byte("secret"), time.Minute)
+	expired.now = func() time.Time { return time.Now().Add(time.Hour) }
+
+	cases := []struct {
+		name   string
+		signer *CursorSigner
+		token  string
+		want   error
+	}{
+		{name: "not base64", signer: signer, token: "***", want: ErrCursorMalformed},
+		{name: "too short", signer: signer, token: "YWJj", want: ErrCursorMalformed},
+		{name: "payload modified", signer: signer, token: string(raw), want: ErrCursorTampered},
+		{name: "other key", signer: NewCursorSigner([]byte("other"), 0), token: token, want: ErrCursorTampered},
+		{name: "expired", signer: expired, token: token, want: ErrCursorExpired},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := c.signer.Decode(c.token)
+			var cursorErr *CursorError
+			assert.True(t, errors.As(err, &cursorErr))
+			assert.True(t, errors.Is(err, c.want), "got %v", err)
+		})
+	}
+}
+
+func TestNewPaginationQueryParamsCursor(t *testing.T) {
+	token, err := DefaultCursorSigner.Encode(*NewCursor(PrevPage, []string{"_id"}, "abc"))
+	assert.NoError(t, err)
+
+	r := httptest.NewRequest("GET", "/?page_size=10&cursor="+url.QueryEscape(token), nil)
+	params, err := NewPaginationQueryParams(r)
+	assert.NoError(t, err)
+	assert.Equal(t, PrevPage, params.Type)
+	assert.Equal(t, []interface{}{"abc"}, params.DecodedCursor.Values)
+
+	r = httptest.NewRequest("GET", "/?page_size=10&cursor="+url.QueryEscape(token[1:]), nil)
+	_, err = NewPaginationQueryParams(r)
+	var cursorErr *CursorError
+	assert.True(t, errors.As(err, &cursorErr))
+}
+
+func TestPaginatedResponseSetCursors(t *testing.T) {
+	var resp PaginatedResponse
+	assert.NoError(t, resp.SetCursors(NewCursor(NextPage, []string{"_id"}, "b"), nil))
+	assert.Empty(t, resp.PrevCursor)
+
+	got, err := DefaultCursorSigner.Decode(resp.NextCursor)
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{"b"}, got.Values)
+}
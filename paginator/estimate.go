@@ -0,0 +1,24 @@
+package paginator
+
+import (
+	"context"
+	"database/sql"
+)
+
+// estimatedRowCountQuery reads the row count MariaDB keeps in table statistics.
+const estimatedRowCountQuery = "SELECT TABLE_ROWS FROM information_schema.TABLES WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?"
+
+// RowQuerier is implemented by *sql.DB, *sql.Conn and *sql.Tx
+type RowQuerier interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// EstimateRowCount returns the estimated row count of table from information_schema.
+// It is cheap compared to COUNT(*) but may be off by a large margin for InnoDB tables.
+func EstimateRowCount(ctx context.Context, db RowQuerier, table string) (int64, error) {
+	var rows sql.NullInt64
+	if err := db.QueryRowContext(ctx, estimatedRowCountQuery, table).Scan(&rows); err != nil {
+		return 0, err
+	}
+	return rows.Int64, nil
+}
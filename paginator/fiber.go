@@ -0,0 +1,72 @@
+package paginator
+
+import (
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// LocalsKey is the fiber.Ctx locals key Middleware stores the pagination query params under.
+const LocalsKey = "pagination"
+
+// fiberQueries returns the query args of c keeping repeated keys like sort_by.
+func fiberQueries(c *fiber.Ctx) map[string][]string {
+	values := make(map[string][]string)
+	c.Context().QueryArgs().VisitAll(func(key, value []byte) {
+		k := string(key)
+		values[k] = append(values[k], string(value))
+	})
+	return values
+}
+
+// NewQueryParamsFromFiber parse the query params of the fiber request into the given data type.
+// i must be pointer to the data type.
+func NewQueryParamsFromFiber(i interface{}, c *fiber.Ctx, filter QueryParamFilter) error {
+	return parserFor(filter).ParseFiber(i, c)
+}
+
+// NewMultipartFormParamsFromFiber parse the multipart form params of the fiber request into the given data type.
+// will only used to parse non-binary form data. for parsing Binary data like files should use c.FormFile
+// filter.MaxMemory is not used, the body size is limited by the fiber.Config of the app.
+// i must be pointer to the data type.
+func NewMultipartFormParamsFromFiber(i interface{}, c *fiber.Ctx, filter MultipartFormParamFilter) *fiber.Error {
+	return parserFor(QueryParamFilter{IgnoreUnknownKeys: filter.IgnoreUnknownKeys}).ParseMultipartFiber(i, c)
+}
+
+// FromFiber parse the fiber request to get pagination query params.
+func FromFiber(c *fiber.Ctx) (*PaginationQueryParam, error) {
+	return FromFiberF(c, QueryParamFilter{})
+}
+
+// FromFiberF parse the fiber request to get pagination query params with filter
+func FromFiberF(c *fiber.Ctx, filter QueryParamFilter) (*PaginationQueryParam, error) {
+	return parserFor(filter).PaginationQueryParamsFromFiber(c)
+}
+
+// Middleware parse and validate the pagination query params and store them in c.Locals under LocalsKey.
+// Invalid params are answered with 400 Bad Request problem details, see WriteProblem.
+func Middleware(filter QueryParamFilter) fiber.Handler {
+	return parserFor(filter).Middleware()
+}
+
+// Middleware parse and validate the pagination query params using p and store them in c.Locals under LocalsKey.
+// Invalid params are answered with 400 Bad Request problem details, see WriteProblem.
+func (p *Parser) Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		params, err := p.PaginationQueryParamsFromFiber(c)
+		if err != nil {
+			if _, ok := AsValidationError(err); !ok {
+				err = fiber.NewError(http.StatusBadRequest, err.Error())
+			}
+			return WriteProblem(c, err)
+		}
+		c.Locals(LocalsKey, params)
+		return c.Next()
+	}
+}
+
+// FromLocals returns the pagination query params stored by Middleware, nil when missing.
+func FromLocals(c *fiber.Ctx) *PaginationQueryParam {
+	params, _ := c.Locals(LocalsKey).(*PaginationQueryParam)
+	return params
+}
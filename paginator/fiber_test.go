@@ -0,0 +1,68 @@
+package paginator_test
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/a01k-io/modules/paginator"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFiberMiddleware(t *testing.T) {
+	app := fiber.New()
+	app.Get("/orders", paginator.Middleware(paginator.QueryParamFilter{}), func(c *fiber.Ctx) error {
+		return c.JSON(paginator.FromLocals(c))
+	})
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/orders?page_no=2&page_size=10&sort_by=name:asc&sort_by=created_at:desc", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var body bytes.Buffer
+	_, _ = body.ReadFrom(resp.Body)
+	assert.JSONEq(t, `{"page_no":2,"page_size":10,"last_id":"","pagination_type":"","sort_by":["name:asc","created_at:desc"],"cursor":"","estimate_total":false,"q":"","fields":null}`, body.String())
+
+	resp, err = app.Test(httptest.NewRequest("GET", "/orders?page_no=1&page_size=1000", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestNewMultipartFormParamsFromFiber(t *testing.T) {
+	type form struct {
+		Name  string `schema:"name"`
+		Count int    `schema:"count"`
+	}
+
+	app := fiber.New()
+	app.Post("/upload", func(c *fiber.Ctx) error {
+		var f form
+		if err := paginator.NewMultipartFormParamsFromFiber(&f, c, paginator.MultipartFormParamFilter{}); err != nil {
+			return err
+		}
+		return c.JSON(f)
+	})
+
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	_ = w.WriteField("name", "report")
+	_ = w.WriteField("count", "3")
+	_ = w.Close()
+	req := httptest.NewRequest("POST", "/upload", &body)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+
+	resp, err := app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var out bytes.Buffer
+	_, _ = out.ReadFrom(resp.Body)
+	assert.JSONEq(t, `{"Name":"report","Count":3}`, out.String())
+
+	resp, err = app.Test(httptest.NewRequest("POST", "/upload", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
@@ -0,0 +1,168 @@
+package paginator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/a01k-io/modules/arrayops"
+	"gorm.io/gorm/clause"
+)
+
+// FieldSpec maps the public field keys of an endpoint to DB fields or columns for the fields param.
+// Keys which are not declared are rejected, so clients can only select the declared fields.
+//
+//	userFields := paginator.NewFieldSpec().Allow("name", "name").Allow("email", "email").Always("tenant_id")
+type FieldSpec struct {
+	fields map[string]string
+	keys   []string
+	always []string
+}
+
+// NewFieldSpec returns an empty FieldSpec.
+func NewFieldSpec() *FieldSpec {
+	return &FieldSpec{fields: make(map[string]string)}
+}
+
+// Allow declares key as selectable and maps it to the DB field.
+func (s *FieldSpec) Allow(key, field string) *FieldSpec {
+	if _, ok := s.fields[key]; !ok {
+		s.keys = append(s.keys, key)
+	}
+	s.fields[key] = field
+	return s
+}
+
+// Always declares DB fields returned whatever the fields param asks for.
+func (s *FieldSpec) Always(fields ...string) *FieldSpec {
+	s.always = append(s.always, fields...)
+	return s
+}
+
+// Keys returns the declared public field keys in declaration order.
+func (s *FieldSpec) Keys() []string {
+	return append([]string(nil), s.keys...)
+}
+
+// Projection is the resolved fields param.
+type Projection struct {
+	// Exclude is set when the fields param lists "-" prefixed keys, the listed fields are left out.
+	Exclude bool
+	// Fields are the DB fields to return, or to leave out when Exclude is set.
+	Fields []string
+}
+
+// Resolve validates the fields param values against the spec, e.g. "name,email" or "-internal_notes".
+// Inclusion and exclusion can not be mixed. Fields declared by Always are added to an inclusion and
+// dropped from an exclusion. It returns nil when values select nothing.
+func (s *FieldSpec) Resolve(values []string) (*Projection, error) {
+	validationErr := &ValidationError{}
+	var projection *Projection
+	for _, value := range values {
+		for _, key := range strings.Split(value, ",") {
+			key = strings.TrimSpace(key)
+			if key == "" {
+				continue
+			}
+			exclude := strings.HasPrefix(key, "-")
+			key = strings.TrimPrefix(key, "-")
+			if projection == nil {
+				projection = &Projection{Exclude: exclude}
+			}
+			if exclude != projection.Exclude {
+				validationErr.Add("fields", CodeInvalid, "fields can not mix included and excluded keys", value)
+				continue
+			}
+			field, ok := s.fields[key]
+			if !ok {
+				validationErr.Add("fields", CodeUnknown, fmt.Sprintf("invalid fields key: %v", key), key)
+				continue
+			}
+			projection.Fields = appendUnique(projection.Fields, field)
+		}
+	}
+	if err := validationErr.Err(); err != nil {
+		return nil, err
+	}
+	if projection == nil {
+		return nil, nil
+	}
+	return projection.With(s.always...), nil
+}
+
+// With returns a copy of the projection which also returns required, they are added to an inclusion
+// and dropped from an exclusion.
+func (p *Projection) With(required ...string) *Projection {
+	projection := &Projection{Exclude: p.Exclude, Fields: make([]string, 0, len(p.Fields)+len(required))}
+	if !p.Exclude {
+		projection.Fields = append(projection.Fields, p.Fields...)
+		for _, f := range required {
+			projection.Fields = appendUnique(projection.Fields, f)
+		}
+		return projection
+	}
+	for _, f := range p.Fields {
+		if !arrayops.ContainsString(required, f) {
+			projection.Fields = append(projection.Fields, f)
+		}
+	}
+	return projection
+}
+
+// IsEmpty reports whether the projection returns the full record.
+func (p *Projection) IsEmpty() bool {
+	return p == nil || (p.Exclude && len(p.Fields) == 0)
+}
+
+// Projection returns the resolved fields param against the policy FieldSpec, nil when fields is empty.
+func (p *PaginationQueryParam) Projection() (*Projection, error) {
+	if len(p.Fields) == 0 {
+		return nil, nil
+	}
+	spec := p.policy().Fields
+	if spec == nil {
+		return nil, &ValidationError{Fields: []FieldError{{
+			Field: "fields", Code: CodeInvalid, Message: "fields is not supported", Value: strings.Join(p.Fields, ","),
+		}}}
+	}
+	return spec.Resolve(p.Fields)
+}
+
+// BuildSelectQuery builds the SELECT clause of the fields param, the sort and IDColumn columns are always selected.
+// An exclusion selects the columns declared in the policy FieldSpec except the excluded ones.
+// params are expected to be validated, no clause is returned without a projection.
+func BuildSelectQuery(params PaginationQueryParam) []clause.Expression {
+	projection, _ := params.Projection()
+	if projection.IsEmpty() {
+		return make([]clause.Expression, 0)
+	}
+	required := CursorColumns(params)
+
+	columns := projection.Fields
+	if projection.Exclude {
+		spec := params.policy().Fields
+		columns = make([]string, 0, len(spec.keys)+len(spec.always))
+		for _, k := range spec.keys {
+			if field := spec.fields[k]; !arrayops.ContainsString(projection.Fields, field) {
+				columns = appendUnique(columns, field)
+			}
+		}
+		required = append(required, spec.always...)
+	}
+	for _, c := range required {
+		columns = appendUnique(columns, c)
+	}
+
+	selectColumns := make([]clause.Column, 0, len(columns))
+	for _, c := range columns {
+		selectColumns = append(selectColumns, clause.Column{Name: c})
+	}
+	return []clause.Expression{clause.Select{Columns: selectColumns}}
+}
+
+// appendUnique appends v to values unless it is already there.
+func appendUnique(values []string, v string) []string {
+	if arrayops.ContainsString(values, v) {
+		return values
+	}
+	return append(values, v)
+}
@@ -0,0 +1,109 @@
+package paginator_test
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/a01k-io/modules/paginator"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm/clause"
+)
+
+func userFieldSpec() *paginator.FieldSpec {
+	return paginator.NewFieldSpec().
+		Allow("name", "name").
+		Allow("email", "email_address").
+		Allow("internal_notes", "internal_notes").
+		Always("tenant_id")
+}
+
+func TestFieldSpecResolve(t *testing.T) {
+	cases := []struct {
+		name       string
+		values     []string
+		want       *paginator.Projection
+		wantFields []string
+	}{
+		{name: "empty", values: []string{" , "}},
+		{name: "inclusion adds always fields", values: []string{"name, email", "name"}, want: &paginator.Projection{Fields: []string{"name", "email_address", "tenant_id"}}},
+		{name: "exclusion", values: []string{"-internal_notes"}, want: &paginator.Projection{Exclude: true, Fields: []string{"internal_notes"}}},
+		{name: "unknown key", values: []string{"name,password"}, wantFields: []string{"fields"}},
+		{name: "mixed inclusion and exclusion", values: []string{"name,-email"}, wantFields: []string{"fields"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			projection, err := userFieldSpec().Resolve(c.values)
+			if len(c.wantFields) != 0 {
+				var validationErr *paginator.ValidationError
+				assert.True(t, errors.As(err, &validationErr))
+				assert.Equal(t, c.wantFields[0], validationErr.Fields[0].Field)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, c.want, projection)
+		})
+	}
+}
+
+func TestProjectionWith(t *testing.T) {
+	include := &paginator.Projection{Fields: []string{"name"}}
+	assert.Equal(t, []string{"name", "created_at", "_id"}, include.With("created_at", "_id").Fields)
+	assert.Equal(t, []string{"name"}, include.Fields)
+
+	exclude := &paginator.Projection{Exclude: true, Fields: []string{"internal_notes", "created_at"}}
+	assert.Equal(t, []string{"internal_notes"}, exclude.With("created_at", "_id").Fields)
+	assert.True(t, (&paginator.Projection{Exclude: true}).IsEmpty())
+}
+
+func TestNewPaginationQueryParamsFields(t *testing.T) {
+	policy := &paginator.PaginationPolicy{DefaultPageSize: 10, MinPageSize: 1, MaxPageSize: 50, Fields: userFieldSpec()}
+
+	params, err := paginator.NewPaginationQueryParamsF(httptest.NewRequest("GET", "/?page_no=1&fields=name,email", nil), paginator.QueryParamFilter{Policy: policy})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"name,email"}, params.Fields)
+
+	_, err = paginator.NewPaginationQueryParamsF(httptest.NewRequest("GET", "/?page_no=1&fields=password", nil), paginator.QueryParamFilter{Policy: policy})
+	assert.EqualError(t, err, "invalid query params: fields: invalid fields key: password")
+
+	_, err = paginator.NewPaginationQueryParams(httptest.NewRequest("GET", "/?page_no=1&fields=name", nil))
+	assert.EqualError(t, err, "invalid query params: fields: fields is not supported")
+}
+
+func TestBuildSelectQuery(t *testing.T) {
+	policy := &paginator.PaginationPolicy{DefaultPageSize: 10, MinPageSize: 1, MaxPageSize: 50, Fields: userFieldSpec()}
+	columns := func(names ...string) []clause.Expression {
+		selectColumns := make([]clause.Column, 0, len(names))
+		for _, n := range names {
+			selectColumns = append(selectColumns, clause.Column{Name: n})
+		}
+		return []clause.Expression{clause.Select{Columns: selectColumns}}
+	}
+
+	cases := []struct {
+		name   string
+		params paginator.PaginationQueryParam
+		want   []clause.Expression
+	}{
+		{
+			name:   "no fields",
+			params: paginator.PaginationQueryParam{Policy: policy},
+			want:   []clause.Expression{},
+		},
+		{
+			name:   "inclusion keeps sort and id columns",
+			params: paginator.PaginationQueryParam{Fields: []string{"email"}, SortBy: []string{"createdAt:desc"}, Policy: policy},
+			want:   columns("email_address", "tenant_id", "created_at", "id"),
+		},
+		{
+			name:   "exclusion selects the other declared columns",
+			params: paginator.PaginationQueryParam{Fields: []string{"-internal_notes"}, Policy: policy},
+			want:   columns("name", "email_address", "id", "tenant_id"),
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert.Equal(t, c.want, paginator.BuildSelectQuery(c.params))
+		})
+	}
+}
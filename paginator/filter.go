@@ -0,0 +1,272 @@
+package paginator
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/a01k-io/modules/stringops"
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// FilterOperator defines comparison operator of filter query param
+type FilterOperator string
+
+const (
+	//FilterEq matches values equal to the given value
+	FilterEq FilterOperator = "eq"
+	//FilterNe matches values not equal to the given value
+	FilterNe FilterOperator = "ne"
+	//FilterGt matches values greater than the given value
+	FilterGt FilterOperator = "gt"
+	//FilterGte matches values greater than or equal to the given value
+	FilterGte FilterOperator = "gte"
+	//FilterLt matches values less than the given value
+	FilterLt FilterOperator = "lt"
+	//FilterLte matches values less than or equal to the given value
+	FilterLte FilterOperator = "lte"
+	//FilterIn matches any of the given values
+	FilterIn FilterOperator = "in"
+	//FilterNin matches none of the given values
+	FilterNin FilterOperator = "nin"
+)
+
+// FilterFieldType defines type the filter value is converted to
+type FilterFieldType string
+
+const (
+	//FilterString keeps the value as string
+	FilterString FilterFieldType = "string"
+	//FilterInt converts the value to int64
+	FilterInt FilterFieldType = "int"
+	//FilterFloat converts the value to float64
+	FilterFloat FilterFieldType = "float"
+	//FilterBool converts the value to bool
+	FilterBool FilterFieldType = "bool"
+	//FilterTime converts RFC3339 or date only value to time.Time
+	FilterTime FilterFieldType = "time"
+	//FilterObjectID converts hex value to primitive.ObjectID
+	FilterObjectID FilterFieldType = "object_id"
+)
+
+const (
+	filterParam          = "filter"
+	filterListSeparator  = ","
+	filterPartSeparator  = ":"
+	filterValueSeparator = "|"
+)
+
+// defaultFilterOperators contains operators allowed per type when FilterField.Operators is empty
+var defaultFilterOperators = map[FilterFieldType][]FilterOperator{
+	FilterString:   {FilterEq, FilterNe, FilterIn, FilterNin},
+	FilterInt:      {FilterEq, FilterNe, FilterGt, FilterGte, FilterLt, FilterLte, FilterIn, FilterNin},
+	FilterFloat:    {FilterEq, FilterNe, FilterGt, FilterGte, FilterLt, FilterLte, FilterIn, FilterNin},
+	FilterBool:     {FilterEq, FilterNe},
+	FilterTime:     {FilterEq, FilterNe, FilterGt, FilterGte, FilterLt, FilterLte},
+	FilterObjectID: {FilterEq, FilterNe, FilterIn, FilterNin},
+}
+
+// FilterError is returned when a filter query param is rejected
+type FilterError struct {
+	Key     string
+	Message string
+}
+
+func (e *FilterError) Error() string {
+	return fmt.Sprintf("invalid filter %v: %v", e.Key, e.Message)
+}
+
+// Field returns the query param the error belongs to.
+func (e *FilterError) Field() string {
+	return filterParam
+}
+
+// FilterField declares a filterable field
+type FilterField struct {
+	// Field is the DB field or column, defaults to the public key
+	Field string
+	Type  FilterFieldType
+	// Operators allowed on the field, defaults to all operators supported by Type
+	Operators []FilterOperator
+}
+
+// FilterCondition is a validated filter on a DB field with its value converted to the field type.
+// Value holds []interface{} for FilterIn and FilterNin.
+type FilterCondition struct {
+	Field    string
+	Operator FilterOperator
+	Value    interface{}
+}
+
+// FilterSchema declares the filterable public keys of an endpoint
+type FilterSchema map[string]FilterField
+
+// ParseRequest parse filter query params of the incoming http request.
+func (s FilterSchema) ParseRequest(r *http.Request) ([]FilterCondition, error) {
+	return s.Parse(r.URL.Query())
+}
+
+// Parse converts filter query params into conditions validated against the schema.
+// Both the list style `filter=status:eq:active,amount:gte:100,tags:in:a|b`
+// and the bracket style `price[gte]=10` are accepted. Other params, including bracket style params
+// whose key is not declared in the schema, are ignored.
+func (s FilterSchema) Parse(values url.Values) ([]FilterCondition, error) {
+	conditions := make([]FilterCondition, 0)
+	for _, list := range values[filterParam] {
+		for _, item := range strings.Split(list, filterListSeparator) {
+			if stringops.IsBlank(item) {
+				continue
+			}
+			parts := strings.SplitN(item, filterPartSeparator, 3)
+			if len(parts) != 3 {
+				return nil, &FilterError{Key: item, Message: "expected key:operator:value"}
+			}
+			condition, err := s.condition(parts[0], parts[1], parts[2])
+			if err != nil {
+				return nil, err
+			}
+			conditions = append(conditions, condition)
+		}
+	}
+
+	params := make([]string, 0, len(values))
+	for param := range values {
+		params = append(params, param)
+	}
+	sort.Strings(params)
+	for _, param := range params {
+		if param == filterParam || !isFilterParam(param) {
+			continue
+		}
+		open := strings.IndexByte(param, '[')
+		if _, ok := s[strings.TrimSpace(param[:open])]; !ok {
+			continue
+		}
+		for _, v := range values[param] {
+			condition, err := s.condition(param[:open], param[open+1:len(param)-1], v)
+			if err != nil {
+				return nil, err
+			}
+			conditions = append(conditions, condition)
+		}
+	}
+	return conditions, nil
+}
+
+// isFilterParam reports whether param is a filter query param, `filter` or the bracket style `price[gte]`.
+func isFilterParam(param string) bool {
+	return param == filterParam || (strings.IndexByte(param, '[') > 0 && strings.HasSuffix(param, "]"))
+}
+
+// withoutFilterParams returns values without the filter query params, values itself when it has none.
+func withoutFilterParams(values map[string][]string) map[string][]string {
+	for k := range values {
+		if !isFilterParam(k) {
+			continue
+		}
+		filtered := make(map[string][]string, len(values))
+		for k, v := range values {
+			if !isFilterParam(k) {
+				filtered[k] = v
+			}
+		}
+		return filtered
+	}
+	return values
+}
+
+func (s FilterSchema) condition(key, operator, value string) (FilterCondition, error) {
+	key = strings.TrimSpace(key)
+	field, ok := s[key]
+	if !ok {
+		return FilterCondition{}, &FilterError{Key: key, Message: "field is not filterable"}
+	}
+	op := FilterOperator(strings.ToLower(strings.TrimSpace(operator)))
+	allowed := field.Operators
+	if len(allowed) == 0 {
+		allowed = defaultFilterOperators[field.Type]
+	}
+	if !containsFilterOperator(allowed, op) {
+		return FilterCondition{}, &FilterError{Key: key, Message: fmt.Sprintf("operator %v is not allowed", operator)}
+	}
+
+	name := field.Field
+	if name == "" {
+		name = key
+	}
+	if op == FilterIn || op == FilterNin {
+		raw := strings.Split(value, filterValueSeparator)
+		list := make([]interface{}, 0, len(raw))
+		for _, r := range raw {
+			v, err := convertFilterValue(field.Type, r)
+			if err != nil {
+				return FilterCondition{}, &FilterError{Key: key, Message: err.Error()}
+			}
+			list = append(list, v)
+		}
+		return FilterCondition{Field: name, Operator: op, Value: list}, nil
+	}
+
+	v, err := convertFilterValue(field.Type, value)
+	if err != nil {
+		return FilterCondition{}, &FilterError{Key: key, Message: err.Error()}
+	}
+	return FilterCondition{Field: name, Operator: op, Value: v}, nil
+}
+
+func containsFilterOperator(operators []FilterOperator, op FilterOperator) bool {
+	for _, o := range operators {
+		if o == op {
+			return true
+		}
+	}
+	return false
+}
+
+// convertFilterValue converts raw into the go type of t.
+func convertFilterValue(t FilterFieldType, raw string) (interface{}, error) {
+	raw = strings.TrimSpace(raw)
+	switch t {
+	case FilterString:
+		return raw, nil
+	case FilterInt:
+		v, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return nil, errors.Errorf("invalid integer value: %v", raw)
+		}
+		return v, nil
+	case FilterFloat:
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, errors.Errorf("invalid number value: %v", raw)
+		}
+		return v, nil
+	case FilterBool:
+		v, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, errors.Errorf("invalid boolean value: %v", raw)
+		}
+		return v, nil
+	case FilterTime:
+		if v, err := time.Parse(time.RFC3339, raw); err == nil {
+			return v, nil
+		}
+		v, err := time.Parse(time.DateOnly, raw)
+		if err != nil {
+			return nil, errors.Errorf("invalid time value: %v", raw)
+		}
+		return v, nil
+	case FilterObjectID:
+		v, err := primitive.ObjectIDFromHex(raw)
+		if err != nil {
+			return nil, errors.Errorf("invalid id value: %v", raw)
+		}
+		return v, nil
+	}
+	return nil, errors.Errorf("unsupported field type: %v", t)
+}
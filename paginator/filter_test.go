@@ -0,0 +1,117 @@
+package paginator_test
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/a01k-io/modules/paginator"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm/clause"
+)
+
+var orderFilterSchema = paginator.FilterSchema{
+	"status":  {Type: paginator.FilterString, Operators: []paginator.FilterOperator{paginator.FilterEq, paginator.FilterIn}},
+	"amount":  {Type: paginator.FilterInt},
+	"tags":    {Field: "labels", Type: paginator.FilterString},
+	"price":   {Type: paginator.FilterFloat},
+	"created": {Field: "created_at", Type: paginator.FilterTime},
+}
+
+func TestFilterSchemaParse(t *testing.T) {
+	cases := []struct {
+		name    string
+		query   string
+		want    []paginator.FilterCondition
+		wantErr error
+	}{
+		{
+			name:  "list style",
+			query: "filter=status:eq:active,amount:gte:100,tags:in:a|b&page_no=1",
+			want: []paginator.FilterCondition{
+				{Field: "status", Operator: paginator.FilterEq, Value: "active"},
+				{Field: "amount", Operator: paginator.FilterGte, Value: int64(100)},
+				{Field: "labels", Operator: paginator.FilterIn, Value: []interface{}{"a", "b"}},
+			},
+		},
+		{
+			name:  "bracket style",
+			query: "price[gte]=10.5&created[lt]=2024-07-01T10:00:00Z",
+			want: []paginator.FilterCondition{
+				{Field: "created_at", Operator: paginator.FilterLt, Value: time.Date(2024, 7, 1, 10, 0, 0, 0, time.UTC)},
+				{Field: "price", Operator: paginator.FilterGte, Value: 10.5},
+			},
+		},
+		{
+			name:  "unknown bracket style params ignored",
+			query: "page[size]=10&amount[gt]=5",
+			want: []paginator.FilterCondition{
+				{Field: "amount", Operator: paginator.FilterGt, Value: int64(5)},
+			},
+		},
+		{
+			name:    "unknown field",
+			query:   "filter=password:eq:x",
+			wantErr: &paginator.FilterError{Key: "password", Message: "field is not filterable"},
+		},
+		{
+			name:    "operator not allowed",
+			query:   "status[ne]=active",
+			wantErr: &paginator.FilterError{Key: "status", Message: "operator ne is not allowed"},
+		},
+		{
+			name:    "invalid value",
+			query:   "filter=amount:gt:ten",
+			wantErr: &paginator.FilterError{Key: "amount", Message: "invalid integer value: ten"},
+		},
+		{
+			name:    "malformed item",
+			query:   "filter=amount",
+			wantErr: &paginator.FilterError{Key: "amount", Message: "expected key:operator:value"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			values, err := url.ParseQuery(c.query)
+			assert.NoError(t, err)
+
+			got, err := orderFilterSchema.Parse(values)
+			assert.Equal(t, c.wantErr, err)
+			assert.Equal(t, c.want, got)
+		})
+	}
+}
+
+func TestBuildFilterQuery(t *testing.T) {
+	got := paginator.BuildFilterQuery([]paginator.FilterCondition{
+		{Field: "status", Operator: paginator.FilterEq, Value: "active"},
+		{Field: "amount", Operator: paginator.FilterGte, Value: int64(100)},
+		{Field: "labels", Operator: paginator.FilterNin, Value: []interface{}{"a", "b"}},
+	})
+	assert.Equal(t, []clause.Expression{clause.Where{Exprs: []clause.Expression{
+		clause.Eq{Column: clause.Column{Name: "status"}, Value: "active"},
+		clause.Gte{Column: clause.Column{Name: "amount"}, Value: int64(100)},
+		clause.Not(clause.IN{Column: clause.Column{Name: "labels"}, Values: []interface{}{"a", "b"}}),
+	}}}, got)
+
+	assert.Empty(t, paginator.BuildFilterQuery(nil))
+}
+
+func TestFilterAndPaginationFromOneRequest(t *testing.T) {
+	r := httptest.NewRequest("GET", "/orders?page_no=1&page_size=10&filter=status:eq:paid&amount[gte]=100", nil)
+
+	params, err := paginator.NewPaginationQueryParams(r)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(10), params.PageSize)
+
+	conditions, err := orderFilterSchema.ParseRequest(r)
+	assert.NoError(t, err)
+	assert.Equal(t, []paginator.FilterCondition{
+		{Field: "status", Operator: paginator.FilterEq, Value: "paid"},
+		{Field: "amount", Operator: paginator.FilterGte, Value: int64(100)},
+	}, conditions)
+
+	_, err = paginator.NewPaginationQueryParams(httptest.NewRequest("GET", "/orders?page_no=1&page_size=10&unknown=1", nil))
+	assert.Error(t, err, "other unknown keys are still rejected")
+}
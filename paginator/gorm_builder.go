@@ -3,10 +3,36 @@ package paginator
 import (
 	"strings"
 
+	"github.com/pkg/errors"
 	"gorm.io/gorm/clause"
 	"gorm.io/gorm/schema"
 )
 
+// IDColumn is the column appended to keyset ORDER BY clauses as tie-breaker.
+const IDColumn = "id"
+
+// ErrCursorSortMismatch means the cursor was issued for a different sort_by.
+var ErrCursorSortMismatch = errors.New("cursor does not match sort_by")
+
+// mapSortingFields maps the sorting fields of params to columns.
+// With SortSpec, on the params or their Policy, the declared columns are used as is and unresolvable sort_by yields no columns,
+// otherwise the keys are snake-cased.
+func mapSortingFields(params PaginationQueryParam) ([]clause.OrderByColumn, error) {
+	spec := params.sortSpec()
+	if spec == nil {
+		return mapSortByToDefault(params.SortBy), nil
+	}
+	sortingFields, err := spec.Resolve(params.SortBy)
+	if err != nil {
+		return make([]clause.OrderByColumn, 0), err
+	}
+	columns := make([]clause.OrderByColumn, 0, len(sortingFields))
+	for _, f := range sortingFields {
+		columns = append(columns, clause.OrderByColumn{Column: clause.Column{Name: f.Key}, Desc: f.OrderBy == OrderByDesc})
+	}
+	return columns, nil
+}
+
 func mapSortByToDefault(sortBy []string) []clause.OrderByColumn {
 	columns := make([]clause.OrderByColumn, 0)
 	for _, sort := range sortBy {
@@ -24,25 +50,172 @@ func mapSortByToDefault(sortBy []string) []clause.OrderByColumn {
 }
 
 // BuildPaginationQuery builds pagination SQL clauses without directly using gorm.DB.
+// params are expected to be validated, sort_by rejected by params.SortSpec is dropped,
+// use BuildOffsetPaginationQuery to get the error instead.
 func BuildPaginationQuery(params PaginationQueryParam) []clause.Expression {
-	sortByColumns := mapSortByToDefault(params.SortBy)
+	clauses, _ := buildOffsetPaginationQuery(params)
+	return clauses
+}
+
+// BuildOffsetPaginationQuery builds the ORDER BY, LIMIT and OFFSET clauses of offset pages.
+// sort_by rejected by params.SortSpec yields the error, the page size is moved into the range of
+// params.Policy and in CountFree mode one extra row is fetched.
+func BuildOffsetPaginationQuery(params PaginationQueryParam) ([]clause.Expression, error) {
+	clauses, err := buildOffsetPaginationQuery(params)
+	if err != nil {
+		return nil, err
+	}
+	return clauses, nil
+}
+
+// buildOffsetPaginationQuery returns the clauses of offset pages, without ORDER BY when sort_by is rejected, and the sort_by error.
+func buildOffsetPaginationQuery(params PaginationQueryParam) ([]clause.Expression, error) {
+	sortByColumns, err := mapSortingFields(params)
 	clauses := make([]clause.Expression, 0)
 	if len(sortByColumns) > 0 {
 		clauses = append(clauses, clause.OrderBy{
 			Columns: sortByColumns,
 		})
 	}
-	if params.PageSize > 0 {
+	if pageSize := params.EffectivePageSize(); pageSize > 0 {
 		offset := 0
 		if params.PageNo > 1 {
-			offset = int((params.PageNo - 1) * params.PageSize)
+			offset = int((params.PageNo - 1) * pageSize)
 		}
 
 		clauses = append(clauses, clause.Limit{
-			Limit:  params.PageSizePInt(),
+			Limit:  intPInt(int(params.FetchLimit())),
 			Offset: offset,
 		})
 	}
 
-	return clauses
+	return clauses, err
+}
+
+// BuildKeysetPaginationQuery builds seek pagination SQL clauses.
+// ORDER BY always ends with IDColumn as tie-breaker and no OFFSET is used. When params carry
+// a decoded cursor a WHERE clause selects the rows after the cursor tuple, for previous pages,
+// per the type of the cursor, the ordering is inverted and the rows have to be passed through Reverse before display.
+// In CountFree mode one extra row is fetched. Relevance sorted searches yield ErrSearchCursor.
+func BuildKeysetPaginationQuery(params PaginationQueryParam) ([]clause.Expression, error) {
+	if params.IsRelevanceSorted() {
+		return nil, ErrSearchCursor
+	}
+	columns, err := keysetColumns(params)
+	if err != nil {
+		return nil, err
+	}
+	clauses := make([]clause.Expression, 0, 3)
+
+	if params.DecodedCursor != nil {
+		where, err := keysetWhere(columns, params.DecodedCursor)
+		if err != nil {
+			return nil, err
+		}
+		clauses = append(clauses, where)
+		if params.DecodedCursor.Type == PrevPage {
+			for i := range columns {
+				columns[i].Desc = !columns[i].Desc
+			}
+		}
+	}
+
+	clauses = append(clauses, clause.OrderBy{Columns: columns})
+	if limit := params.FetchLimit(); limit > 0 {
+		clauses = append(clauses, clause.Limit{Limit: intPInt(int(limit))})
+	}
+	return clauses, nil
+}
+
+// CursorColumns returns the column names, in order, a keyset cursor for params has to carry.
+func CursorColumns(params PaginationQueryParam) []string {
+	columns, _ := keysetColumns(params)
+	names := make([]string, 0, len(columns))
+	for _, c := range columns {
+		names = append(names, c.Column.Name)
+	}
+	return names
+}
+
+// keysetColumns maps the sorting fields of params to columns and appends IDColumn following the direction of the last column.
+func keysetColumns(params PaginationQueryParam) ([]clause.OrderByColumn, error) {
+	columns, err := mapSortingFields(params)
+	if err != nil {
+		return nil, err
+	}
+	desc := true
+	for _, c := range columns {
+		if c.Column.Name == IDColumn {
+			return columns, nil
+		}
+		desc = c.Desc
+	}
+	return append(columns, clause.OrderByColumn{Column: clause.Column{Name: IDColumn}, Desc: desc}), nil
+}
+
+// keysetWhere returns the expanded lexicographic condition selecting rows after the cursor tuple:
+//
+//	(c0 > v0) OR (c0 = v0 AND c1 > v1) OR ...
+//
+// where the comparison of every column follows its direction and is flipped for previous pages.
+func keysetWhere(columns []clause.OrderByColumn, cursor *Cursor) (clause.Where, error) {
+	if len(cursor.Keys) != len(columns) {
+		return clause.Where{}, ErrCursorSortMismatch
+	}
+	for i, c := range columns {
+		if cursor.Keys[i] != c.Column.Name {
+			return clause.Where{}, ErrCursorSortMismatch
+		}
+	}
+
+	branches := make([]clause.Expression, 0, len(columns))
+	for i, c := range columns {
+		exprs := make([]clause.Expression, 0, i+1)
+		for j := 0; j < i; j++ {
+			exprs = append(exprs, clause.Eq{Column: columns[j].Column, Value: cursor.Values[j]})
+		}
+		after := !c.Desc
+		if cursor.Type == PrevPage {
+			after = !after
+		}
+		if after {
+			exprs = append(exprs, clause.Gt{Column: c.Column, Value: cursor.Values[i]})
+		} else {
+			exprs = append(exprs, clause.Lt{Column: c.Column, Value: cursor.Values[i]})
+		}
+		branches = append(branches, clause.And(exprs...))
+	}
+	return clause.Where{Exprs: []clause.Expression{clause.Or(branches...)}}, nil
+}
+
+// BuildFilterQuery builds the WHERE clause for filter conditions parsed by FilterSchema.
+func BuildFilterQuery(conditions []FilterCondition) []clause.Expression {
+	if len(conditions) == 0 {
+		return make([]clause.Expression, 0)
+	}
+	exprs := make([]clause.Expression, 0, len(conditions))
+	for _, c := range conditions {
+		column := clause.Column{Name: c.Field}
+		switch c.Operator {
+		case FilterEq:
+			exprs = append(exprs, clause.Eq{Column: column, Value: c.Value})
+		case FilterNe:
+			exprs = append(exprs, clause.Neq{Column: column, Value: c.Value})
+		case FilterGt:
+			exprs = append(exprs, clause.Gt{Column: column, Value: c.Value})
+		case FilterGte:
+			exprs = append(exprs, clause.Gte{Column: column, Value: c.Value})
+		case FilterLt:
+			exprs = append(exprs, clause.Lt{Column: column, Value: c.Value})
+		case FilterLte:
+			exprs = append(exprs, clause.Lte{Column: column, Value: c.Value})
+		case FilterIn:
+			values, _ := c.Value.([]interface{})
+			exprs = append(exprs, clause.IN{Column: column, Values: values})
+		case FilterNin:
+			values, _ := c.Value.([]interface{})
+			exprs = append(exprs, clause.Not(clause.IN{Column: column, Values: values}))
+		}
+	}
+	return []clause.Expression{clause.Where{Exprs: exprs}}
 }
@@ -81,3 +81,111 @@ func TestBuildPaginationQuery(t *testing.T) {
 		})
 	}
 }
+
+func TestBuildKeysetPaginationQuery(t *testing.T) {
+	tests := []struct {
+		name            string
+		params          paginator.PaginationQueryParam
+		expectedClauses []clause.Expression
+		expectedErr     error
+	}{
+		{
+			name: "First page orders by tie-breaker without offset",
+			params: paginator.PaginationQueryParam{
+				PageSize: 6,
+				SortBy:   []string{"created_at:desc"},
+			},
+			expectedClauses: []clause.Expression{
+				clause.OrderBy{
+					Columns: []clause.OrderByColumn{
+						{Column: clause.Column{Name: "created_at"}, Desc: true},
+						{Column: clause.Column{Name: "id"}, Desc: true},
+					},
+				},
+				clause.Limit{Limit: pointy.Pointer(6)},
+			},
+		},
+		{
+			name: "Next page seeks after cursor",
+			params: paginator.PaginationQueryParam{
+				PageSize:      6,
+				Type:          paginator.NextPage,
+				SortBy:        []string{"created_at:desc"},
+				DecodedCursor: paginator.NewCursor(paginator.NextPage, []string{"created_at", "id"}, int64(100), int64(7)),
+			},
+			expectedClauses: []clause.Expression{
+				clause.Where{Exprs: []clause.Expression{clause.Or(
+					clause.Lt{Column: clause.Column{Name: "created_at"}, Value: int64(100)},
+					clause.And(
+						clause.Eq{Column: clause.Column{Name: "created_at"}, Value: int64(100)},
+						clause.Lt{Column: clause.Column{Name: "id"}, Value: int64(7)},
+					),
+				)}},
+				clause.OrderBy{
+					Columns: []clause.OrderByColumn{
+						{Column: clause.Column{Name: "created_at"}, Desc: true},
+						{Column: clause.Column{Name: "id"}, Desc: true},
+					},
+				},
+				clause.Limit{Limit: pointy.Pointer(6)},
+			},
+		},
+		{
+			name: "Prev page seeks before cursor with inverted ordering",
+			params: paginator.PaginationQueryParam{
+				PageSize:      6,
+				Type:          paginator.PrevPage,
+				SortBy:        []string{"id:asc"},
+				DecodedCursor: paginator.NewCursor(paginator.PrevPage, []string{"id"}, int64(7)),
+			},
+			expectedClauses: []clause.Expression{
+				clause.Where{Exprs: []clause.Expression{clause.Or(
+					clause.Lt{Column: clause.Column{Name: "id"}, Value: int64(7)},
+				)}},
+				clause.OrderBy{
+					Columns: []clause.OrderByColumn{
+						{Column: clause.Column{Name: "id"}, Desc: true},
+					},
+				},
+				clause.Limit{Limit: pointy.Pointer(6)},
+			},
+		},
+		{
+			name: "Cursor type wins over a mismatching pagination type",
+			params: paginator.PaginationQueryParam{
+				PageSize:      6,
+				Type:          paginator.NextPage,
+				SortBy:        []string{"id:asc"},
+				DecodedCursor: paginator.NewCursor(paginator.PrevPage, []string{"id"}, int64(7)),
+			},
+			expectedClauses: []clause.Expression{
+				clause.Where{Exprs: []clause.Expression{clause.Or(
+					clause.Lt{Column: clause.Column{Name: "id"}, Value: int64(7)},
+				)}},
+				clause.OrderBy{
+					Columns: []clause.OrderByColumn{
+						{Column: clause.Column{Name: "id"}, Desc: true},
+					},
+				},
+				clause.Limit{Limit: pointy.Pointer(6)},
+			},
+		},
+		{
+			name: "Cursor issued for another sort",
+			params: paginator.PaginationQueryParam{
+				PageSize:      6,
+				SortBy:        []string{"created_at:desc"},
+				DecodedCursor: paginator.NewCursor(paginator.NextPage, []string{"id"}, int64(7)),
+			},
+			expectedErr: paginator.ErrCursorSortMismatch,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actualClauses, err := paginator.BuildKeysetPaginationQuery(tt.params)
+			assert.Equal(t, tt.expectedErr, err)
+			assert.Equal(t, tt.expectedClauses, actualClauses)
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package paginator
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/a01k-io/modules/stringops"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Page contain typed records, pagination info and navigation metadata
+type Page[T any] struct {
+	Items      []T            `json:"items"`
+	Pagination PaginationInfo `json:"pagination"`
+	HasNext    bool           `json:"has_next"`
+	HasPrev    bool           `json:"has_prev"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+	PrevCursor string         `json:"prev_cursor,omitempty"`
+}
+
+// NewPage returns offset page of items out of totalCount records.
+func NewPage[T any](items []T, params PaginationQueryParam, totalCount int64) Page[T] {
+	if items == nil {
+		items = make([]T, 0)
+	}
+	info := newPaginationInfo(params, totalCount)
+	return Page[T]{
+		Items:      items,
+		Pagination: info,
+		HasNext:    info.PageNo < info.TotalPages,
+		HasPrev:    info.PageNo > 1,
+	}
+}
+
+// NewHasMorePage returns page of items fetched in CountFree mode without a total count.
+// The extra row is trimmed and sets HasNext, or HasPrev for previous keyset pages which are
+// reversed into display order.
+func NewHasMorePage[T any](items []T, params PaginationQueryParam) Page[T] {
+	if items == nil {
+		items = make([]T, 0)
+	}
+	pageSize := params.EffectivePageSize()
+	more := pageSize > 0 && int64(len(items)) > pageSize
+	if more {
+		items = items[:pageSize]
+	}
+
+	page := Page[T]{
+		Items:      items,
+		Pagination: PaginationInfo{PageNo: params.PageNo, PageSize: pageSize},
+	}
+	switch {
+	case params.Reversed():
+		Reverse(items)
+		page.HasPrev, page.HasNext = more, true
+	case params.DecodedCursor != nil || !stringops.IsBlank(params.LastID):
+		page.HasNext, page.HasPrev = more, true
+	default:
+		page.HasNext, page.HasPrev = more, params.PageNo > 1
+	}
+	return page
+}
+
+// SetEstimatedTotal sets an estimated total count, e.g. from EstimateRowCount or the
+// EstimatedDocumentCount of a mongo collection.
+func (p *Page[T]) SetEstimatedTotal(total int64) {
+	p.Pagination.TotalCount = total
+	p.Pagination.TotalEstimated = true
+	if p.Pagination.PageSize > 0 {
+		p.Pagination.TotalPages = (total + p.Pagination.PageSize - 1) / p.Pagination.PageSize
+	}
+}
+
+// newPaginationInfo returns pagination info of offset pages, TotalPages is zero when page size is not positive.
+func newPaginationInfo(params PaginationQueryParam, totalCount int64) PaginationInfo {
+	pageSize := params.EffectivePageSize()
+	var totalPages int64
+	if pageSize > 0 {
+		totalPages = (totalCount + pageSize - 1) / pageSize
+	}
+	return PaginationInfo{
+		PageNo:     params.PageNo,
+		PageSize:   pageSize,
+		TotalPages: totalPages,
+		TotalCount: totalCount,
+	}
+}
+
+// SetCursors encodes next and prev using DefaultCursorSigner and sets HasNext and HasPrev,
+// nil cursors are left empty.
+func (p *Page[T]) SetCursors(next, prev *Cursor) error {
+	return p.SetSignedCursors(DefaultCursorSigner, next, prev)
+}
+
+// SetSignedCursors is SetCursors encoding with signer.
+func (p *Page[T]) SetSignedCursors(signer *CursorSigner, next, prev *Cursor) error {
+	var resp PaginatedResponse
+	if err := resp.SetSignedCursors(signer, next, prev); err != nil {
+		return err
+	}
+	p.NextCursor, p.PrevCursor = resp.NextCursor, resp.PrevCursor
+	p.HasNext, p.HasPrev = next != nil, prev != nil
+	return nil
+}
+
+// Links returns the RFC 8288 Link header value with first, prev, next and last relations
+// built from the original request URL u, other query params are kept as is.
+// Cursor pages link by cursor and have no last relation, offset pages link by page_no.
+func (p Page[T]) Links(u *url.URL) string {
+	links := make([]string, 0, 4)
+	add := func(rel string, set map[string]string) {
+		query := u.Query()
+		for _, k := range []string{"page_no", "cursor", "last_id", "pagination_type"} {
+			query.Del(k)
+		}
+		for k, v := range set {
+			query.Set(k, v)
+		}
+		link := *u
+		link.RawQuery = query.Encode()
+		links = append(links, fmt.Sprintf(`<%s>; rel="%s"`, link.String(), rel))
+	}
+
+	page := func(n int64) map[string]string {
+		return map[string]string{"page_no": strconv.FormatInt(n, 10)}
+	}
+	if p.NextCursor != "" || p.PrevCursor != "" {
+		add("first", page(1))
+		if p.HasPrev && p.PrevCursor != "" {
+			add("prev", map[string]string{"cursor": p.PrevCursor})
+		}
+		if p.HasNext && p.NextCursor != "" {
+			add("next", map[string]string{"cursor": p.NextCursor})
+		}
+		return strings.Join(links, ", ")
+	}
+
+	add("first", page(1))
+	if p.HasPrev {
+		add("prev", page(p.Pagination.PageNo-1))
+	}
+	if p.HasNext {
+		add("next", page(p.Pagination.PageNo+1))
+	}
+	if p.Pagination.TotalPages > 0 {
+		add("last", page(p.Pagination.TotalPages))
+	}
+	return strings.Join(links, ", ")
+}
+
+// SetLinkHeader sets the Link header of the fiber response, see Links.
+func (p Page[T]) SetLinkHeader(c *fiber.Ctx) error {
+	u, err := url.ParseRequestURI(c.OriginalURL())
+	if err != nil {
+		return err
+	}
+	c.Set(fiber.HeaderLink, p.Links(u))
+	return nil
+}
@@ -0,0 +1,142 @@
+package paginator_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/a01k-io/modules/paginator"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+type pageRecord struct {
+	ID string `json:"id"`
+}
+
+func TestNewPage(t *testing.T) {
+	page := paginator.NewPage([]pageRecord{{ID: "a"}}, paginator.PaginationQueryParam{PageNo: 2, PageSize: 10}, 25)
+	assert.Equal(t, paginator.PaginationInfo{PageNo: 2, PageSize: 10, TotalPages: 3, TotalCount: 25}, page.Pagination)
+	assert.True(t, page.HasNext)
+	assert.True(t, page.HasPrev)
+
+	empty := paginator.NewPage[pageRecord](nil, paginator.PaginationQueryParam{PageNo: 1, PageSize: 10}, 0)
+	assert.Equal(t, []pageRecord{}, empty.Items)
+	assert.False(t, empty.HasNext)
+	assert.False(t, empty.HasPrev)
+}
+
+func TestCreatePaginatedAPIResponseZeroPageSize(t *testing.T) {
+	params := paginator.PaginationQueryParam{PageNo: 1, Policy: &paginator.PaginationPolicy{}}
+	resp := paginator.CreatePaginatedAPIResponse([]pageRecord{{ID: "a"}}, params, 5)
+	assert.Equal(t, int64(20), resp.Pagination.PageSize)
+	assert.Equal(t, int64(1), resp.Pagination.TotalPages)
+}
+
+func TestPageLinks(t *testing.T) {
+	u, _ := url.Parse("/orders?page_no=2&page_size=10&status=active")
+
+	page := paginator.NewPage([]pageRecord{{ID: "a"}}, paginator.PaginationQueryParam{PageNo: 2, PageSize: 10}, 35)
+	assert.Equal(t, `</orders?page_no=1&page_size=10&status=active>; rel="first", `+
+		`</orders?page_no=1&page_size=10&status=active>; rel="prev", `+
+		`</orders?page_no=3&page_size=10&status=active>; rel="next", `+
+		`</orders?page_no=4&page_size=10&status=active>; rel="last"`, page.Links(u))
+
+	cursorPage := paginator.Page[pageRecord]{HasNext: true, NextCursor: "abc", PrevCursor: "xyz"}
+	assert.Equal(t, `</orders?page_no=1&page_size=10&status=active>; rel="first", `+
+		`</orders?cursor=abc&page_size=10&status=active>; rel="next"`, cursorPage.Links(u))
+}
+
+func TestPageLinksFirstParses(t *testing.T) {
+	u, _ := url.Parse("/orders?cursor=abc&page_size=10")
+	cursorPage := paginator.Page[pageRecord]{HasNext: true, NextCursor: "abc"}
+	links := strings.Split(cursorPage.Links(u), ", ")
+	first := strings.TrimSuffix(strings.TrimPrefix(links[0], "<"), `>; rel="first"`)
+	assert.Equal(t, "/orders?page_no=1&page_size=10", first)
+
+	params, err := paginator.NewPaginationQueryParams(httptest.NewRequest("GET", first, nil))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), params.PageNo)
+}
+
+func TestPageSetLinkHeader(t *testing.T) {
+	app := fiber.New()
+	app.Get("/orders", func(c *fiber.Ctx) error {
+		params, err := paginator.FromFiber(c)
+		if err != nil {
+			return err
+		}
+		page := paginator.NewPage([]pageRecord{{ID: "a"}}, *params, 1)
+		if err := page.SetLinkHeader(c); err != nil {
+			return err
+		}
+		return c.JSON(page)
+	})
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/orders?page_no=1&page_size=10", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, `</orders?page_no=1&page_size=10>; rel="first", </orders?page_no=1&page_size=10>; rel="last"`, resp.Header.Get("Link"))
+}
+
+func TestNewHasMorePage(t *testing.T) {
+	countFree := &paginator.PaginationPolicy{DefaultPageSize: 2, MinPageSize: 1, MaxPageSize: 10, CountFree: true}
+	records := func(ids ...string) []pageRecord {
+		items := make([]pageRecord, 0, len(ids))
+		for _, id := range ids {
+			items = append(items, pageRecord{ID: id})
+		}
+		return items
+	}
+
+	cases := []struct {
+		name     string
+		params   paginator.PaginationQueryParam
+		items    []pageRecord
+		want     []pageRecord
+		wantNext bool
+		wantPrev bool
+	}{
+		{
+			name:     "extra row trimmed",
+			params:   paginator.PaginationQueryParam{PageNo: 1, Policy: countFree},
+			items:    records("a", "b", "c"),
+			want:     records("a", "b"),
+			wantNext: true,
+		},
+		{
+			name:     "last offset page",
+			params:   paginator.PaginationQueryParam{PageNo: 3, Policy: countFree},
+			items:    records("e"),
+			want:     records("e"),
+			wantPrev: true,
+		},
+		{
+			name: "previous keyset page reversed",
+			params: paginator.PaginationQueryParam{
+				Type:          paginator.PrevPage,
+				DecodedCursor: paginator.NewCursor(paginator.PrevPage, []string{"id"}, "d"),
+				Policy:        countFree,
+			},
+			items:    records("c", "b", "a"),
+			want:     records("b", "c"),
+			wantNext: true,
+			wantPrev: true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert.Equal(t, int64(3), c.params.FetchLimit())
+			page := paginator.NewHasMorePage(c.items, c.params)
+			assert.Equal(t, c.want, page.Items)
+			assert.Equal(t, c.wantNext, page.HasNext)
+			assert.Equal(t, c.wantPrev, page.HasPrev)
+		})
+	}
+
+	page := paginator.NewHasMorePage(records("a"), paginator.PaginationQueryParam{PageNo: 1, Policy: countFree})
+	page.SetEstimatedTotal(5)
+	assert.Equal(t, paginator.PaginationInfo{PageNo: 1, PageSize: 2, TotalPages: 3, TotalCount: 5, TotalEstimated: true}, page.Pagination)
+}
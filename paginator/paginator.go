@@ -2,22 +2,22 @@ package paginator
 
 import (
 	"fmt"
-	"math"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/a01k-io/modules/stringops"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gorilla/schema"
-	"github.com/pkg/errors"
 )
 
 var (
 	//URLParamDecoder returns a new Decoder
 	//For parsing url query params
-	URLParamDecoder       = schema.NewDecoder()
-	minPageSize     int64 = 1
-	maxPageSize     int64 = 50
+	//
+	//Deprecated: the package level parse functions use DefaultParser, build a Parser with NewParser
+	//to configure decoding.
+	URLParamDecoder = schema.NewDecoder()
 )
 
 // QueryParamFilter defines settings for query parser
@@ -26,6 +26,10 @@ type QueryParamFilter struct {
 	MaxLen            string
 	IgnoreUnknownKeys bool
 	Strip             bool
+	// SortSpec restricts sort_by to the declared keys when set.
+	SortSpec *SortSpec
+	// Policy defines page size limits and defaults, DefaultPaginationPolicy when nil.
+	Policy *PaginationPolicy
 }
 
 // Filter defines settings for parser
@@ -37,17 +41,7 @@ type Filter struct {
 // NewQueryParamsFromReq parse the incoming http request query params payload into the given data type.
 // i must be pointer to the data type.
 func NewQueryParamsFromReq(i interface{}, r *http.Request, filter QueryParamFilter) error {
-	if err := r.ParseForm(); err != nil {
-		return errors.New("failed to parse query param")
-	}
-	if filter.IgnoreUnknownKeys {
-		URLParamDecoder.IgnoreUnknownKeys(true)
-	}
-
-	if err := URLParamDecoder.Decode(i, r.Form); err != nil {
-		return errors.New("failed to Decode query param")
-	}
-	return nil
+	return parserFor(filter).ParseRequest(i, r)
 }
 
 // MultipartFormParamFilter defines settings for form parser
@@ -60,21 +54,7 @@ type MultipartFormParamFilter struct {
 // will only used to parse non-binary form data. for parsing Binary data like files should use r.FormFile
 // i must be pointer to the data type.
 func NewMultipartFormParamsFromReq(i interface{}, r *http.Request, filter MultipartFormParamFilter) *fiber.Error {
-	var defaultMultipartFormMaxMemory int64 = 32 << 20 // 32 MB
-	if filter.MaxMemory != 0 {
-		defaultMultipartFormMaxMemory = filter.MaxMemory
-	}
-	if e := r.ParseMultipartForm(defaultMultipartFormMaxMemory); e != nil {
-		return fiber.NewError(http.StatusBadRequest, "failed to parse multipart form data.")
-	}
-	if filter.IgnoreUnknownKeys {
-		URLParamDecoder.IgnoreUnknownKeys(true)
-	}
-
-	if e := URLParamDecoder.Decode(i, r.PostForm); e != nil {
-		return fiber.NewError(http.StatusBadRequest, "failed to Decode multipart data")
-	}
-	return nil
+	return parserFor(QueryParamFilter{IgnoreUnknownKeys: filter.IgnoreUnknownKeys}).ParseMultipartForm(i, r, filter.MaxMemory)
 }
 
 // PaginationQueryType defines type of paginated query
@@ -119,29 +99,29 @@ type SortingField struct {
 
 // GetSortingFields convert sorting query params to SortingField and validate it
 func getSortingFields(params []string) ([]*SortingField, error) {
-	var errFields []string
+	validationErr := &ValidationError{}
 	sortingFields := make([]*SortingField, 0, len(params))
 
 	for _, s := range params {
 		sortField := strings.Split(s, ":")
 		if len(sortField) != 2 {
-			errFields = append(errFields, fmt.Sprintf("invalid sort_by value: %v", s))
+			validationErr.Add("sort_by", CodeInvalid, fmt.Sprintf("invalid sort_by value: %v", s), s)
 			continue
 		}
 
 		if stringops.IsBlank(sortField[0]) {
-			errFields = append(errFields, "sort_by field name is required")
+			validationErr.Add("sort_by", CodeRequired, "sort_by field name is required", s)
 		}
 
 		if !OrderDirection(sortField[1]).Valid() {
-			errFields = append(errFields, fmt.Sprintf("invalid sort_by order value: %v", s))
+			validationErr.Add("sort_by", CodeInvalid, fmt.Sprintf("invalid sort_by order value: %v", s), s)
 		}
 
 		sortingFields = append(sortingFields, &SortingField{Key: sortField[0], OrderBy: OrderDirection(sortField[1])})
 	}
 
-	if len(errFields) != 0 {
-		return make([]*SortingField, 0), errors.Wrapf(errors.New("invalid sort_by query params: "), fmt.Sprintf("%v", errFields))
+	if err := validationErr.Err(); err != nil {
+		return make([]*SortingField, 0), err
 	}
 
 	return sortingFields, nil
@@ -154,14 +134,39 @@ type PaginationQueryParam struct {
 	LastID   string              `schema:"last_id" query:"last_id" json:"last_id"`
 	Type     PaginationQueryType `schema:"pagination_type" query:"pagination_type" json:"pagination_type"`
 	SortBy   []string            `schema:"sort_by" query:"sort_by" json:"sort_by"`
+	Cursor   string              `schema:"cursor" query:"cursor" json:"cursor"`
+	// EstimateTotal asks count free endpoints for an estimated total count
+	EstimateTotal bool `schema:"estimate_total" query:"estimate_total" json:"estimate_total"`
+	// Search is the search term matched against the searchable fields of the policy SearchSpec
+	Search string `schema:"q" query:"q" json:"q"`
+	// Fields selects the returned fields e.g. "name,email" or excludes them e.g. "-internal_notes"
+	Fields []string `schema:"fields" query:"fields" json:"fields"`
+
+	// DecodedCursor holds the verified Cursor, it is set by DecodeCursor.
+	DecodedCursor *Cursor `schema:"-" query:"-" json:"-"`
+	// SortSpec restricts and maps sort_by keys to DB fields when set.
+	SortSpec *SortSpec `schema:"-" query:"-" json:"-"`
+	// Policy defines page size limits and defaults, DefaultPaginationPolicy when nil.
+	Policy *PaginationPolicy `schema:"-" query:"-" json:"-"`
+	// Signer verified the cursor, it is set by DecodeCursor and issues the cursors of the page.
+	Signer *CursorSigner `schema:"-" query:"-" json:"-"`
+}
+
+// EffectiveSigner returns the signer cursors of the page are issued with, DefaultCursorSigner when Signer is nil.
+func (p *PaginationQueryParam) EffectiveSigner() *CursorSigner {
+	if p.Signer == nil {
+		return DefaultCursorSigner
+	}
+	return p.Signer
 }
 
 func intPInt(i int) *int {
 	return &i
 }
 
+// PageSizePInt returns pointer to EffectivePageSize
 func (p *PaginationQueryParam) PageSizePInt() *int {
-	return intPInt(int(p.PageSize))
+	return intPInt(int(p.EffectivePageSize()))
 }
 
 // GetSortingFields parse sort query param and return SortingField array
@@ -169,53 +174,71 @@ func (p *PaginationQueryParam) GetSortingFields() ([]*SortingField, error) {
 	return getSortingFields(p.SortBy)
 }
 
-// Validate pagination query params
+// ResolveSortingFields returns the sorting fields keyed by DB field.
+// Without SortSpec, on the params or their Policy, it is same as GetSortingFields.
+func (p *PaginationQueryParam) ResolveSortingFields() ([]*SortingField, error) {
+	spec := p.sortSpec()
+	if spec == nil {
+		return p.GetSortingFields()
+	}
+	return spec.Resolve(p.SortBy)
+}
+
+// DecodeCursor verifies the cursor token using signer and stores it in DecodedCursor.
+// The pagination type is taken from the cursor, signer is kept in Signer to issue the next cursors.
+func (p *PaginationQueryParam) DecodeCursor(signer *CursorSigner) error {
+	p.Signer = signer
+	if stringops.IsBlank(p.Cursor) {
+		return nil
+	}
+	cursor, err := signer.Decode(p.Cursor)
+	if err != nil {
+		return err
+	}
+	p.DecodedCursor = cursor
+	p.Type = cursor.Type
+	return nil
+}
+
+// Validate pagination query params against their Policy, failures are reported as *ValidationError
 func (p *PaginationQueryParam) Validate() error {
-	var errFields []string
+	validationErr := &ValidationError{}
 
-	if !stringops.IsBlank(p.LastID) && !p.Type.Valid() {
-		errFields = append(errFields, "Invalid pagination_type")
-	} else if stringops.IsBlank(p.LastID) && p.PageNo <= 0 {
-		errFields = append(errFields, fmt.Sprintf("invalid page_no value: %v", p.PageNo))
+	if p.DecodedCursor != nil || !stringops.IsBlank(p.LastID) {
+		if !p.Type.Valid() {
+			validationErr.Add("pagination_type", CodeInvalid, "Invalid pagination_type", string(p.Type))
+		}
+	} else if p.PageNo <= 0 {
+		validationErr.Add("page_no", CodeInvalid, fmt.Sprintf("invalid page_no value: %v", p.PageNo), strconv.FormatInt(p.PageNo, 10))
 	}
 
-	if p.PageSize < minPageSize || p.PageSize > maxPageSize {
-		errFields = append(errFields, fmt.Sprintf("invalid page_size value it should be in between [%v - %v]", minPageSize, maxPageSize))
+	policy := p.policy()
+	if p.PageSize < policy.MinPageSize || (policy.MaxPageSize > 0 && p.PageSize > policy.MaxPageSize) {
+		validationErr.Add("page_size", CodeOutOfRange, fmt.Sprintf("invalid page_size value it should be in between [%v - %v]", policy.MinPageSize, policy.MaxPageSize), strconv.FormatInt(p.PageSize, 10))
+	}
+	if policy.MaxPageNo > 0 && p.PageNo > policy.MaxPageNo {
+		validationErr.Add("page_no", CodeOutOfRange, fmt.Sprintf("invalid page_no value it should not exceed %v", policy.MaxPageNo), strconv.FormatInt(p.PageNo, 10))
 	}
 
-	_, sortParamErr := p.GetSortingFields()
-	if sortParamErr != nil {
-		errFields = append(errFields, sortParamErr.Error())
+	if _, sortParamErr := p.ResolveSortingFields(); sortParamErr != nil {
+		validationErr.Merge(sortParamErr)
 	}
-	if len(errFields) != 0 {
-		return errors.Wrapf(errors.New("invalid pagination query params: "), fmt.Sprintf("%v", errFields))
+	p.validateSearch(validationErr)
+	if _, fieldsErr := p.Projection(); fieldsErr != nil {
+		validationErr.Merge(fieldsErr)
 	}
 
-	return nil
+	return validationErr.Err()
 }
 
 // NewPaginationQueryParams parse the incoming http request to get pagination query params.
 func NewPaginationQueryParams(r *http.Request) (*PaginationQueryParam, error) {
-	var params PaginationQueryParam
-	if err := NewQueryParamsFromReq(&params, r, QueryParamFilter{}); err != nil {
-		return nil, err
-	}
-	if err := params.Validate(); err != nil {
-		return nil, err
-	}
-	return &params, nil
+	return NewPaginationQueryParamsF(r, QueryParamFilter{})
 }
 
 // NewPaginationQueryParamsF parse the incoming http request to get pagination query params with filter
 func NewPaginationQueryParamsF(r *http.Request, filter QueryParamFilter) (*PaginationQueryParam, error) {
-	var params PaginationQueryParam
-	if err := NewQueryParamsFromReq(&params, r, filter); err != nil {
-		return nil, err
-	}
-	if err := params.Validate(); err != nil {
-		return nil, err
-	}
-	return &params, nil
+	return parserFor(filter).PaginationQueryParams(r)
 }
 
 // PaginationInfo contain info about pagination
@@ -224,12 +247,41 @@ type PaginationInfo struct {
 	PageSize   int64 `json:"page_size"`
 	TotalPages int64 `json:"total_pages"`
 	TotalCount int64 `json:"total_count"`
+	// TotalEstimated is set when TotalCount is an estimate
+	TotalEstimated bool `json:"total_estimated,omitempty"`
 }
 
 // PaginatedResponse contain records and pagination info
 type PaginatedResponse struct {
 	Records    interface{}    `json:"records"`
 	Pagination PaginationInfo `json:"pagination"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+	PrevCursor string         `json:"prev_cursor,omitempty"`
+}
+
+// SetCursors encodes next and prev using DefaultCursorSigner, nil cursors are left empty.
+func (r *PaginatedResponse) SetCursors(next, prev *Cursor) error {
+	return r.SetSignedCursors(DefaultCursorSigner, next, prev)
+}
+
+// SetSignedCursors encodes next and prev using signer, nil cursors are left empty.
+// Use the signer the request cursor was decoded with, see PaginationQueryParam.EffectiveSigner.
+func (r *PaginatedResponse) SetSignedCursors(signer *CursorSigner, next, prev *Cursor) error {
+	if next != nil {
+		token, err := signer.Encode(*next)
+		if err != nil {
+			return err
+		}
+		r.NextCursor = token
+	}
+	if prev != nil {
+		token, err := signer.Encode(*prev)
+		if err != nil {
+			return err
+		}
+		r.PrevCursor = token
+	}
+	return nil
 }
 
 // CreatePaginatedAPIResponse add pagination info in api response
@@ -238,13 +290,8 @@ func CreatePaginatedAPIResponse(records interface{}, paginatedQueryParam Paginat
 		records = make([]interface{}, 0)
 	}
 	return PaginatedResponse{
-		Records: records,
-		Pagination: PaginationInfo{
-			PageSize:   paginatedQueryParam.PageSize,
-			PageNo:     paginatedQueryParam.PageNo,
-			TotalPages: int64(math.Ceil(float64(totalCount) / float64(paginatedQueryParam.PageSize))),
-			TotalCount: totalCount,
-		},
+		Records:    records,
+		Pagination: newPaginationInfo(paginatedQueryParam, totalCount),
 	}
 }
 
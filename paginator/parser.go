@@ -0,0 +1,225 @@
+package paginator
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gorilla/schema"
+	"github.com/pkg/errors"
+)
+
+var (
+	// DefaultParser is used by the package level parse functions, it rejects unknown keys.
+	DefaultParser = NewParser()
+
+	// lenientParser is used by the package level parse functions when unknown keys are ignored.
+	lenientParser = NewParser(WithIgnoreUnknownKeys(true))
+)
+
+// Parser decodes query and form params into structs.
+// Parser owns its decoder and is not modified after construction, so it is safe for concurrent use
+// and settings of one handler never leak into another.
+type Parser struct {
+	decoder           *schema.Decoder
+	ignoreUnknownKeys bool
+	maxLen            int
+	strip             bool
+	sortSpec          *SortSpec
+	policy            *PaginationPolicy
+	cursorSigner      *CursorSigner
+}
+
+// ParserOption configures Parser
+type ParserOption func(*Parser)
+
+// WithIgnoreUnknownKeys sets whether keys without a matching struct field are ignored or rejected.
+func WithIgnoreUnknownKeys(ignore bool) ParserOption {
+	return func(p *Parser) {
+		p.ignoreUnknownKeys = ignore
+	}
+}
+
+// WithMaxLen rejects values longer than n characters, zero disables the check.
+func WithMaxLen(n int) ParserOption {
+	return func(p *Parser) {
+		p.maxLen = n
+	}
+}
+
+// WithStrip trims leading and trailing white space of every value before decoding.
+func WithStrip(strip bool) ParserOption {
+	return func(p *Parser) {
+		p.strip = strip
+	}
+}
+
+// WithConverter registers converter for the type of value.
+func WithConverter(value interface{}, converter schema.Converter) ParserOption {
+	return func(p *Parser) {
+		p.decoder.RegisterConverter(value, converter)
+	}
+}
+
+// WithSortSpec restricts sort_by of parsed pagination query params to spec.
+func WithSortSpec(spec *SortSpec) ParserOption {
+	return func(p *Parser) {
+		p.sortSpec = spec
+	}
+}
+
+// WithPolicy sets the page size limits and defaults of parsed pagination query params.
+func WithPolicy(policy *PaginationPolicy) ParserOption {
+	return func(p *Parser) {
+		p.policy = policy
+	}
+}
+
+// WithCursorSigner sets the signer used to verify cursors, defaults to DefaultCursorSigner.
+func WithCursorSigner(signer *CursorSigner) ParserOption {
+	return func(p *Parser) {
+		p.cursorSigner = signer
+	}
+}
+
+// NewParser returns Parser with its own decoder configured by opts.
+func NewParser(opts ...ParserOption) *Parser {
+	p := &Parser{decoder: schema.NewDecoder()}
+	for _, opt := range opts {
+		opt(p)
+	}
+	p.decoder.IgnoreUnknownKeys(p.ignoreUnknownKeys)
+	return p
+}
+
+// parserFor returns a Parser applying filter, sharing the decoder of the package level parsers.
+func parserFor(filter QueryParamFilter) *Parser {
+	base := DefaultParser
+	if filter.IgnoreUnknownKeys {
+		base = lenientParser
+	}
+	maxLen, _ := strconv.Atoi(filter.MaxLen)
+	if maxLen == 0 && !filter.Strip && filter.SortSpec == nil && filter.Policy == nil {
+		return base
+	}
+
+	p := *base
+	p.maxLen = maxLen
+	p.strip = filter.Strip
+	p.sortSpec = filter.SortSpec
+	p.policy = filter.Policy
+	return &p
+}
+
+// Decode decodes values into i, i must be pointer to the data type.
+func (p *Parser) Decode(i interface{}, values map[string][]string) error {
+	if p.strip {
+		stripped := make(map[string][]string, len(values))
+		for k, vs := range values {
+			vs = append([]string(nil), vs...)
+			trimArray(vs)
+			stripped[k] = vs
+		}
+		values = stripped
+	}
+	if p.maxLen > 0 {
+		validationErr := &ValidationError{}
+		for k, vs := range values {
+			for _, v := range vs {
+				if len([]rune(v)) > p.maxLen {
+					validationErr.Add(k, CodeTooLong, fmt.Sprintf("query param exceeds max length of %v", p.maxLen), v)
+				}
+			}
+		}
+		if err := validationErr.Err(); err != nil {
+			sort.Slice(validationErr.Fields, func(i, j int) bool {
+				return validationErr.Fields[i].Field < validationErr.Fields[j].Field
+			})
+			return err
+		}
+	}
+
+	if err := p.decoder.Decode(i, values); err != nil {
+		return newDecodeError(err, values)
+	}
+	return nil
+}
+
+// ParseRequest parse the query params of the incoming http request into i.
+func (p *Parser) ParseRequest(i interface{}, r *http.Request) error {
+	if err := r.ParseForm(); err != nil {
+		return errors.New("failed to parse query param")
+	}
+	return p.Decode(i, r.Form)
+}
+
+// ParseFiber parse the query params of the fiber request into i.
+func (p *Parser) ParseFiber(i interface{}, c *fiber.Ctx) error {
+	return p.Decode(i, fiberQueries(c))
+}
+
+// ParseMultipartForm parse the non-binary multipart form params of the incoming http request into i.
+// maxMemory of zero defaults to 32 MB.
+func (p *Parser) ParseMultipartForm(i interface{}, r *http.Request, maxMemory int64) *fiber.Error {
+	if maxMemory == 0 {
+		maxMemory = 32 << 20 // 32 MB
+	}
+	if e := r.ParseMultipartForm(maxMemory); e != nil {
+		return fiber.NewError(http.StatusBadRequest, "failed to parse multipart form data.")
+	}
+	return p.decodeMultipartForm(i, r.PostForm)
+}
+
+// ParseMultipartFiber parse the non-binary multipart form params of the fiber request into i.
+func (p *Parser) ParseMultipartFiber(i interface{}, c *fiber.Ctx) *fiber.Error {
+	form, err := c.MultipartForm()
+	if err != nil {
+		return fiber.NewError(http.StatusBadRequest, "failed to parse multipart form data.")
+	}
+	return p.decodeMultipartForm(i, form.Value)
+}
+
+func (p *Parser) decodeMultipartForm(i interface{}, values map[string][]string) *fiber.Error {
+	if e := p.Decode(i, values); e != nil {
+		return fiber.NewError(http.StatusBadRequest, "failed to Decode multipart data")
+	}
+	return nil
+}
+
+// PaginationQueryParams parse and validate the pagination query params of the incoming http request.
+func (p *Parser) PaginationQueryParams(r *http.Request) (*PaginationQueryParam, error) {
+	if err := r.ParseForm(); err != nil {
+		return nil, errors.New("failed to parse query param")
+	}
+	return p.paginationQueryParams(r.Form)
+}
+
+// PaginationQueryParamsFromFiber parse and validate the pagination query params of the fiber request.
+func (p *Parser) PaginationQueryParamsFromFiber(c *fiber.Ctx) (*PaginationQueryParam, error) {
+	return p.paginationQueryParams(fiberQueries(c))
+}
+
+// paginationQueryParams decodes, verifies the cursor and validates pagination query params from values.
+// The filter params of FilterSchema are left out, so both can be parsed from one request.
+func (p *Parser) paginationQueryParams(values map[string][]string) (*PaginationQueryParam, error) {
+	var params PaginationQueryParam
+	if err := p.Decode(&params, withoutFilterParams(values)); err != nil {
+		return nil, err
+	}
+	params.SortSpec = p.sortSpec
+	params.Policy = p.policy
+	params.ApplyPolicy()
+	signer := p.cursorSigner
+	if signer == nil {
+		signer = DefaultCursorSigner
+	}
+	if err := params.DecodeCursor(signer); err != nil {
+		return nil, err
+	}
+	if err := params.Validate(); err != nil {
+		return nil, err
+	}
+	return &params, nil
+}
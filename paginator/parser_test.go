@@ -0,0 +1,97 @@
+package paginator_test
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/a01k-io/modules/paginator"
+	"github.com/stretchr/testify/assert"
+)
+
+type upperString string
+
+func TestParserOptions(t *testing.T) {
+	type params struct {
+		Name upperString `schema:"name"`
+	}
+
+	cases := []struct {
+		name    string
+		parser  *paginator.Parser
+		values  map[string][]string
+		want    params
+		wantErr string
+	}{
+		{
+			name:   "strip trims values",
+			parser: paginator.NewParser(paginator.WithStrip(true)),
+			values: map[string][]string{"name": {"  bob "}},
+			want:   params{Name: "bob"},
+		},
+		{
+			name:    "max length rejects long values",
+			parser:  paginator.NewParser(paginator.WithMaxLen(3)),
+			values:  map[string][]string{"name": {"alice"}},
+			wantErr: "invalid query params: name: query param exceeds max length of 3",
+		},
+		{
+			name:    "unknown keys rejected by default",
+			parser:  paginator.NewParser(),
+			values:  map[string][]string{"name": {"bob"}, "other": {"x"}},
+			wantErr: "invalid query params: other: unknown query param",
+		},
+		{
+			name:   "unknown keys ignored",
+			parser: paginator.NewParser(paginator.WithIgnoreUnknownKeys(true)),
+			values: map[string][]string{"name": {"bob"}, "other": {"x"}},
+			want:   params{Name: "bob"},
+		},
+		{
+			name: "custom converter",
+			parser: paginator.NewParser(paginator.WithConverter(upperString(""), func(s string) reflect.Value {
+				return reflect.ValueOf(upperString(strings.ToUpper(s)))
+			})),
+			values: map[string][]string{"name": {"bob"}},
+			want:   params{Name: "BOB"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var got params
+			err := c.parser.Decode(&got, c.values)
+			if c.wantErr != "" {
+				assert.EqualError(t, err, c.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, c.want, got)
+		})
+	}
+}
+
+func TestIgnoreUnknownKeysDoesNotLeak(t *testing.T) {
+	type params struct {
+		Name string `schema:"name"`
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			var p params
+			r := httptest.NewRequest("GET", "/?name=a&other=b", nil)
+			assert.NoError(t, paginator.NewQueryParamsFromReq(&p, r, paginator.QueryParamFilter{IgnoreUnknownKeys: true}))
+		}()
+		go func() {
+			defer wg.Done()
+			var p params
+			r := httptest.NewRequest("GET", "/?name=a&other=b", nil)
+			assert.Error(t, paginator.NewQueryParamsFromReq(&p, r, paginator.QueryParamFilter{}))
+		}()
+	}
+	wg.Wait()
+}
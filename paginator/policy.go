@@ -0,0 +1,113 @@
+package paginator
+
+// DefaultPaginationPolicy is used when the pagination query params carry no policy.
+var DefaultPaginationPolicy = PaginationPolicy{
+	DefaultPageSize: 20,
+	MinPageSize:     1,
+	MaxPageSize:     50,
+}
+
+// PaginationPolicy defines the page size limits and defaults of an endpoint
+//
+//	exportPolicy := paginator.PaginationPolicy{DefaultPageSize: 100, MinPageSize: 1, MaxPageSize: 500}
+type PaginationPolicy struct {
+	// DefaultPageSize is used when page_size is missing
+	DefaultPageSize int64
+	MinPageSize     int64
+	MaxPageSize     int64
+	// ClampPageSize moves an out of range page_size into [MinPageSize - MaxPageSize] instead of rejecting it
+	ClampPageSize bool
+	// SortSpec restricts sort_by to the allowed keys, it is used when the params have no SortSpec of their own
+	SortSpec *SortSpec
+	// MaxPageNo rejects deeper offset pages, zero means unlimited
+	MaxPageNo int64
+	// CountFree makes the query builders fetch one row more than the page size instead of
+	// relying on a total count, see NewHasMorePage.
+	CountFree bool
+	// Search declares the searchable fields, the q param is rejected when nil
+	Search *SearchSpec
+	// Fields declares the fields selectable by the fields param, the param is rejected when nil
+	Fields *FieldSpec
+}
+
+// clampPageSize returns size moved into the policy range, missing size yields DefaultPageSize.
+func (p PaginationPolicy) clampPageSize(size int64) int64 {
+	if size <= 0 {
+		size = p.DefaultPageSize
+	}
+	if size < p.MinPageSize {
+		size = p.MinPageSize
+	}
+	if p.MaxPageSize > 0 && size > p.MaxPageSize {
+		size = p.MaxPageSize
+	}
+	return size
+}
+
+// withDefaults fills the unset page size bounds, so that a partially filled policy never yields
+// an unlimited query: a missing MinPageSize is 1 and a missing DefaultPageSize is the one of
+// DefaultPaginationPolicy, capped by MaxPageSize.
+func (p PaginationPolicy) withDefaults() PaginationPolicy {
+	if p.MinPageSize <= 0 {
+		p.MinPageSize = 1
+	}
+	if p.DefaultPageSize <= 0 {
+		p.DefaultPageSize = DefaultPaginationPolicy.DefaultPageSize
+		if p.MaxPageSize > 0 && p.DefaultPageSize > p.MaxPageSize {
+			p.DefaultPageSize = p.MaxPageSize
+		}
+	}
+	return p
+}
+
+// policy returns the policy of the params, DefaultPaginationPolicy when none is set.
+func (p *PaginationQueryParam) policy() PaginationPolicy {
+	if p.Policy == nil {
+		return DefaultPaginationPolicy
+	}
+	return p.Policy.withDefaults()
+}
+
+// sortSpec returns the SortSpec of the params, the policy SortSpec when none is set.
+func (p *PaginationQueryParam) sortSpec() *SortSpec {
+	if p.SortSpec != nil {
+		return p.SortSpec
+	}
+	return p.policy().SortSpec
+}
+
+// ApplyPolicy fills the missing page_size with the policy default and clamps page_size when the
+// policy asks for it.
+func (p *PaginationQueryParam) ApplyPolicy() {
+	policy := p.policy()
+	if p.PageSize == 0 {
+		p.PageSize = policy.DefaultPageSize
+	}
+	if policy.ClampPageSize {
+		p.PageSize = policy.clampPageSize(p.PageSize)
+	}
+}
+
+// EffectivePageSize returns the page size the query builders use, page_size moved into the range of Policy.
+// Without Policy page_size is used as is, zero meaning no limit.
+func (p *PaginationQueryParam) EffectivePageSize() int64 {
+	if p.Policy == nil {
+		return p.PageSize
+	}
+	return p.policy().clampPageSize(p.PageSize)
+}
+
+// FetchLimit returns the number of rows the query builders fetch,
+// one more than EffectivePageSize in CountFree mode.
+func (p *PaginationQueryParam) FetchLimit() int64 {
+	pageSize := p.EffectivePageSize()
+	if pageSize > 0 && p.IsCountFree() {
+		return pageSize + 1
+	}
+	return pageSize
+}
+
+// IsCountFree reports whether the policy of the params is in CountFree mode.
+func (p *PaginationQueryParam) IsCountFree() bool {
+	return p.policy().CountFree
+}
@@ -0,0 +1,95 @@
+package paginator_test
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/a01k-io/modules/paginator"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"go.openly.dev/pointy"
+	"gorm.io/gorm/clause"
+)
+
+func TestPaginationPolicy(t *testing.T) {
+	exportPolicy := &paginator.PaginationPolicy{DefaultPageSize: 100, MinPageSize: 1, MaxPageSize: 500}
+	mobilePolicy := &paginator.PaginationPolicy{DefaultPageSize: 20, MinPageSize: 5, MaxPageSize: 20, ClampPageSize: true, MaxPageNo: 10}
+	maxOnlyPolicy := &paginator.PaginationPolicy{MaxPageSize: 10}
+
+	cases := []struct {
+		name         string
+		query        string
+		policy       *paginator.PaginationPolicy
+		wantPageSize int64
+		wantFields   []string
+	}{
+		{name: "missing page_size falls back to package default", query: "page_no=1", wantPageSize: 20},
+		{name: "missing page_size falls back to policy default", query: "page_no=1", policy: exportPolicy, wantPageSize: 100},
+		{name: "export page size accepted", query: "page_no=1&page_size=500", policy: exportPolicy, wantPageSize: 500},
+		{name: "out of range page size rejected", query: "page_no=1&page_size=501", policy: exportPolicy, wantFields: []string{"page_size"}},
+		{name: "out of range page size clamped to max", query: "page_no=1&page_size=200", policy: mobilePolicy, wantPageSize: 20},
+		{name: "out of range page size clamped to min", query: "page_no=1&page_size=2", policy: mobilePolicy, wantPageSize: 5},
+		{name: "page number above max rejected", query: "page_no=11&page_size=20", policy: mobilePolicy, wantFields: []string{"page_no"}},
+		{name: "missing policy default capped by max", query: "page_no=1", policy: maxOnlyPolicy, wantPageSize: 10},
+		{name: "zero page size falls back to default", query: "page_no=1&page_size=0", policy: maxOnlyPolicy, wantPageSize: 10},
+		{name: "negative page size rejected without min", query: "page_no=1&page_size=-1", policy: maxOnlyPolicy, wantFields: []string{"page_size"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			params, err := paginator.NewPaginationQueryParamsF(httptest.NewRequest("GET", "/?"+c.query, nil), paginator.QueryParamFilter{Policy: c.policy})
+			if len(c.wantFields) != 0 {
+				var validationErr *paginator.ValidationError
+				assert.True(t, errors.As(err, &validationErr))
+				fields := make([]string, 0)
+				for _, f := range validationErr.Fields {
+					fields = append(fields, f.Field)
+				}
+				assert.Equal(t, c.wantFields, fields)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, c.wantPageSize, params.PageSize)
+		})
+	}
+}
+
+func TestPaginationPolicyBuilders(t *testing.T) {
+	params := paginator.PaginationQueryParam{
+		PageNo:   2,
+		PageSize: 1000,
+		SortBy:   []string{"created:desc"},
+		Policy: &paginator.PaginationPolicy{
+			DefaultPageSize: 10,
+			MinPageSize:     1,
+			MaxPageSize:     100,
+			SortSpec:        paginator.NewSortSpec().Allow("created", "created_at"),
+		},
+	}
+	assert.Equal(t, []clause.Expression{
+		clause.OrderBy{Columns: []clause.OrderByColumn{{Column: clause.Column{Name: "created_at"}, Desc: true}}},
+		clause.Limit{Limit: pointy.Pointer(100), Offset: 100},
+	}, paginator.BuildPaginationQuery(params))
+
+	params.PageSize = 0
+	assert.Equal(t, int64(10), params.EffectivePageSize())
+}
+
+func TestEffectivePageSizePartialPolicy(t *testing.T) {
+	params := paginator.PaginationQueryParam{PageNo: 1, Policy: &paginator.PaginationPolicy{MaxPageSize: 100}}
+	assert.Equal(t, paginator.DefaultPaginationPolicy.DefaultPageSize, params.EffectivePageSize())
+	assert.Equal(t, []clause.Expression{
+		clause.Limit{Limit: pointy.Pointer(20)},
+	}, paginator.BuildPaginationQuery(params))
+}
+
+func TestEffectivePageSizeWithoutPolicy(t *testing.T) {
+	params := paginator.PaginationQueryParam{PageNo: 2, PageSize: 1000}
+	assert.Equal(t, int64(1000), params.EffectivePageSize())
+	assert.Equal(t, []clause.Expression{
+		clause.Limit{Limit: pointy.Pointer(1000), Offset: 1000},
+	}, paginator.BuildPaginationQuery(params))
+
+	params.PageSize = 0
+	assert.Equal(t, int64(0), params.EffectivePageSize())
+	assert.Empty(t, paginator.BuildPaginationQuery(params))
+}
@@ -0,0 +1,144 @@
+package paginator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm/clause"
+)
+
+// DefaultSearchMaxLen is the max length of the search term when SearchSpec.MaxLen is zero.
+const DefaultSearchMaxLen = 100
+
+// SearchScoreField is the field, or column alias, holding the relevance score of text search.
+// It is underscore prefixed so it does not overwrite a score field of the documents.
+const SearchScoreField = "_score"
+
+// ErrSearchCursor means a cursor was used with a relevance sorted search, relevance pages are offset pages.
+var ErrSearchCursor = errors.New("relevance sorted search does not support cursors")
+
+// SearchMode defines how the search term is matched
+type SearchMode int
+
+const (
+	// SearchText matches through the full-text index, Mongo $text or MariaDB MATCH ... AGAINST.
+	// Without sort_by results are ordered by relevance.
+	SearchText SearchMode = iota
+	// SearchPrefix matches the fields starting with the term, case-insensitive.
+	// It needs no text index and is meant for small collections and short fields like names or codes.
+	SearchPrefix
+)
+
+// SearchSpec declares the searchable fields of an endpoint
+//
+//	orderSearch := &paginator.SearchSpec{Fields: []string{"code", "customer_name"}, Mode: paginator.SearchPrefix}
+type SearchSpec struct {
+	// Fields are the DB fields matched by SearchPrefix, for SearchText the columns of the FULLTEXT index.
+	// Mongo $text always uses the text index of the collection.
+	Fields []string
+	Mode   SearchMode
+	// MaxLen rejects longer search terms, zero means DefaultSearchMaxLen.
+	MaxLen int
+}
+
+// searchSpec returns the search spec of the params policy, nil when search is not supported.
+func (p *PaginationQueryParam) searchSpec() *SearchSpec {
+	return p.policy().Search
+}
+
+// SearchTerm returns the trimmed search term, empty when the endpoint does not support search.
+func (p *PaginationQueryParam) SearchTerm() string {
+	if p.searchSpec() == nil {
+		return ""
+	}
+	return strings.TrimSpace(p.Search)
+}
+
+// SearchSpec returns the search spec the term is matched with, nil when there is no search.
+func (p *PaginationQueryParam) SearchSpec() *SearchSpec {
+	if p.SearchTerm() == "" {
+		return nil
+	}
+	return p.searchSpec()
+}
+
+// IsRelevanceSorted reports whether the results are ordered by text search relevance,
+// that is a SearchText term without sort_by.
+func (p *PaginationQueryParam) IsRelevanceSorted() bool {
+	spec := p.SearchSpec()
+	return spec != nil && spec.Mode == SearchText && len(p.SortBy) == 0
+}
+
+// validateSearch adds the search term failures to validationErr.
+func (p *PaginationQueryParam) validateSearch(validationErr *ValidationError) {
+	term := strings.TrimSpace(p.Search)
+	if term == "" {
+		return
+	}
+	spec := p.searchSpec()
+	if spec == nil {
+		validationErr.Add("q", CodeInvalid, "search is not supported", p.Search)
+		return
+	}
+	maxLen := spec.MaxLen
+	if maxLen == 0 {
+		maxLen = DefaultSearchMaxLen
+	}
+	if len([]rune(term)) > maxLen {
+		validationErr.Add("q", CodeTooLong, fmt.Sprintf("q exceeds max length of %v", maxLen), p.Search)
+	}
+	if p.DecodedCursor != nil && p.IsRelevanceSorted() {
+		validationErr.Add("cursor", CodeInvalid, ErrSearchCursor.Error(), p.Cursor)
+	}
+}
+
+// BuildSearchQuery builds the SQL clauses matching the search term of params.
+// SearchText yields MATCH (columns) AGAINST (term IN NATURAL LANGUAGE MODE) and, when relevance sorted,
+// an ORDER BY on the same expression, use it with BuildPaginationQuery as relevance pages are offset pages.
+// SearchPrefix yields column LIKE 'term%' conditions combined with OR, the term is escaped.
+func BuildSearchQuery(params PaginationQueryParam) []clause.Expression {
+	spec := params.SearchSpec()
+	if spec == nil || len(spec.Fields) == 0 {
+		return make([]clause.Expression, 0)
+	}
+	term := params.SearchTerm()
+
+	if spec.Mode == SearchPrefix {
+		exprs := make([]clause.Expression, 0, len(spec.Fields))
+		for _, f := range spec.Fields {
+			exprs = append(exprs, clause.Like{Column: clause.Column{Name: f}, Value: escapeLike(term) + "%"})
+		}
+		return []clause.Expression{clause.Where{Exprs: []clause.Expression{clause.Or(exprs...)}}}
+	}
+
+	match := matchAgainst(spec.Fields, term)
+	clauses := []clause.Expression{clause.Where{Exprs: []clause.Expression{match}}}
+	if params.IsRelevanceSorted() {
+		clauses = append(clauses, clause.OrderBy{Expression: clause.Expr{
+			SQL:  match.SQL + " DESC, ? DESC",
+			Vars: append(append([]interface{}(nil), match.Vars...), clause.Column{Name: IDColumn}),
+		}})
+	}
+	return clauses
+}
+
+// matchAgainst returns the MariaDB full-text match expression of columns.
+func matchAgainst(columns []string, term string) clause.Expr {
+	vars := make([]interface{}, 0, len(columns)+1)
+	placeholders := make([]string, 0, len(columns))
+	for _, c := range columns {
+		vars = append(vars, clause.Column{Name: c})
+		placeholders = append(placeholders, "?")
+	}
+	vars = append(vars, term)
+	return clause.Expr{
+		SQL:  "MATCH (" + strings.Join(placeholders, ",") + ") AGAINST (? IN NATURAL LANGUAGE MODE)",
+		Vars: vars,
+	}
+}
+
+// escapeLike escapes the LIKE wildcards of s using the default escape character.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
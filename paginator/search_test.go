@@ -0,0 +1,107 @@
+package paginator_test
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/a01k-io/modules/paginator"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"go.openly.dev/pointy"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	textSearchPolicy   = &paginator.PaginationPolicy{DefaultPageSize: 10, MinPageSize: 1, MaxPageSize: 50, Search: &paginator.SearchSpec{Fields: []string{"title", "body"}}}
+	prefixSearchPolicy = &paginator.PaginationPolicy{DefaultPageSize: 10, MinPageSize: 1, MaxPageSize: 50, Search: &paginator.SearchSpec{Fields: []string{"code", "name"}, Mode: paginator.SearchPrefix, MaxLen: 5}}
+)
+
+func TestNewPaginationQueryParamsSearch(t *testing.T) {
+	cases := []struct {
+		name       string
+		query      string
+		policy     *paginator.PaginationPolicy
+		wantTerm   string
+		wantFields []string
+	}{
+		{name: "search term is trimmed", query: "page_no=1&q=+red+shoes+", policy: textSearchPolicy, wantTerm: "red shoes"},
+		{name: "blank search is ignored", query: "page_no=1&q=+", policy: textSearchPolicy},
+		{name: "search without spec rejected", query: "page_no=1&q=red", wantFields: []string{"q"}},
+		{name: "search longer than max rejected", query: "page_no=1&q=abcdef", policy: prefixSearchPolicy, wantFields: []string{"q"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			params, err := paginator.NewPaginationQueryParamsF(httptest.NewRequest("GET", "/?"+c.query, nil), paginator.QueryParamFilter{Policy: c.policy})
+			if len(c.wantFields) != 0 {
+				var validationErr *paginator.ValidationError
+				assert.True(t, errors.As(err, &validationErr))
+				fields := make([]string, 0)
+				for _, f := range validationErr.Fields {
+					fields = append(fields, f.Field)
+				}
+				assert.Equal(t, c.wantFields, fields)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, c.wantTerm, params.SearchTerm())
+		})
+	}
+}
+
+func TestBuildSearchQuery(t *testing.T) {
+	matchVars := []interface{}{clause.Column{Name: "title"}, clause.Column{Name: "body"}, "red shoes"}
+
+	cases := []struct {
+		name   string
+		params paginator.PaginationQueryParam
+		want   []clause.Expression
+	}{
+		{
+			name:   "no search",
+			params: paginator.PaginationQueryParam{Policy: textSearchPolicy},
+			want:   []clause.Expression{},
+		},
+		{
+			name:   "text search ordered by relevance",
+			params: paginator.PaginationQueryParam{Search: "red shoes", Policy: textSearchPolicy},
+			want: []clause.Expression{
+				clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "MATCH (?,?) AGAINST (? IN NATURAL LANGUAGE MODE)", Vars: matchVars}}},
+				clause.OrderBy{Expression: clause.Expr{
+					SQL:  "MATCH (?,?) AGAINST (? IN NATURAL LANGUAGE MODE) DESC, ? DESC",
+					Vars: append(append([]interface{}(nil), matchVars...), clause.Column{Name: "id"}),
+				}},
+			},
+		},
+		{
+			name:   "text search with sort_by",
+			params: paginator.PaginationQueryParam{Search: "red shoes", SortBy: []string{"created_at:desc"}, Policy: textSearchPolicy},
+			want: []clause.Expression{
+				clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "MATCH (?,?) AGAINST (? IN NATURAL LANGUAGE MODE)", Vars: matchVars}}},
+			},
+		},
+		{
+			name:   "prefix search escapes wildcards",
+			params: paginator.PaginationQueryParam{Search: "a_1%", Policy: prefixSearchPolicy},
+			want: []clause.Expression{
+				clause.Where{Exprs: []clause.Expression{clause.Or(
+					clause.Like{Column: clause.Column{Name: "code"}, Value: `a\_1\%%`},
+					clause.Like{Column: clause.Column{Name: "name"}, Value: `a\_1\%%`},
+				)}},
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert.Equal(t, c.want, paginator.BuildSearchQuery(c.params))
+		})
+	}
+}
+
+func TestBuildKeysetPaginationQueryRelevance(t *testing.T) {
+	_, err := paginator.BuildKeysetPaginationQuery(paginator.PaginationQueryParam{Search: "red", PageSize: 10, Policy: textSearchPolicy})
+	assert.ErrorIs(t, err, paginator.ErrSearchCursor)
+
+	clauses, err := paginator.BuildKeysetPaginationQuery(paginator.PaginationQueryParam{Search: "red", PageSize: 10, Policy: prefixSearchPolicy})
+	assert.NoError(t, err)
+	assert.Equal(t, clause.Limit{Limit: pointy.Pointer(10)}, clauses[len(clauses)-1])
+}
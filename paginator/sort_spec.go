@@ -0,0 +1,152 @@
+package paginator
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gorm.io/gorm/schema"
+)
+
+// SortKeyError is returned when sort_by references a key which is not declared in the SortSpec.
+type SortKeyError struct {
+	Key string
+}
+
+func (e *SortKeyError) Error() string {
+	return fmt.Sprintf("invalid sort_by key: %v", e.Key)
+}
+
+// Field returns the query param the error belongs to.
+func (e *SortKeyError) Field() string {
+	return "sort_by"
+}
+
+// SortSpec maps the public sort keys of an endpoint to DB fields or columns.
+// Keys which are not declared are rejected, so clients can only sort on the declared fields.
+type SortSpec struct {
+	fields      map[string]string
+	keys        []string
+	defaultSort []string
+}
+
+// NewSortSpec returns an empty SortSpec.
+func NewSortSpec() *SortSpec {
+	return &SortSpec{fields: make(map[string]string)}
+}
+
+// NewSortSpecFromStruct builds SortSpec from the `sort` tags of struct v.
+// The tag value is the public key, the option `default` or `default=desc` adds the field to the default sort:
+//
+//	CreatedAt time.Time `bson:"created_at" sort:"created,default=desc"`
+//
+// The DB field name is read from fieldTag ("bson", "json" or "gorm" column), falling back to the snake-cased field name.
+func NewSortSpecFromStruct(v interface{}, fieldTag string) *SortSpec {
+	spec := NewSortSpec()
+	var defaultSort []string
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return spec
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag, ok := f.Tag.Lookup("sort")
+		if !ok || tag == "-" {
+			continue
+		}
+		parts := strings.Split(tag, ",")
+		key := parts[0]
+		if key == "" {
+			key = f.Name
+		}
+		spec.Allow(key, structFieldName(f, fieldTag))
+
+		for _, opt := range parts[1:] {
+			switch {
+			case opt == "default":
+				defaultSort = append(defaultSort, key+":"+string(OrderByAsc))
+			case strings.HasPrefix(opt, "default="):
+				defaultSort = append(defaultSort, key+":"+strings.TrimPrefix(opt, "default="))
+			}
+		}
+	}
+	if len(defaultSort) != 0 {
+		spec.Default(defaultSort...)
+	}
+	return spec
+}
+
+// structFieldName returns the DB name of f read from fieldTag.
+func structFieldName(f reflect.StructField, fieldTag string) string {
+	tag := f.Tag.Get(fieldTag)
+	if fieldTag == "gorm" {
+		for _, opt := range strings.Split(tag, ";") {
+			if strings.HasPrefix(opt, "column:") {
+				return strings.TrimPrefix(opt, "column:")
+			}
+		}
+		tag = ""
+	}
+	if name := strings.Split(tag, ",")[0]; name != "" && name != "-" {
+		return name
+	}
+	return schema.NamingStrategy{}.ColumnName("", f.Name)
+}
+
+// Allow declares key as sortable and maps it to the DB field.
+func (s *SortSpec) Allow(key, field string) *SortSpec {
+	if _, ok := s.fields[key]; !ok {
+		s.keys = append(s.keys, key)
+	}
+	s.fields[key] = field
+	return s
+}
+
+// Default sets the sort applied when sort_by is empty, it should be backed by an index.
+// sortBy uses the public keys in sort_by format e.g. "created:desc", the keys must be declared
+// by Allow first. It panics when sortBy is malformed or uses an undeclared key, as the spec is
+// defined once at startup.
+func (s *SortSpec) Default(sortBy ...string) *SortSpec {
+	sortingFields, err := getSortingFields(sortBy)
+	if err != nil {
+		panic(fmt.Sprintf("paginator: invalid default sort: %v", err))
+	}
+	for _, f := range sortingFields {
+		if _, ok := s.fields[f.Key]; !ok {
+			panic(fmt.Sprintf("paginator: invalid default sort: %v", &SortKeyError{Key: f.Key}))
+		}
+	}
+	s.defaultSort = sortBy
+	return s
+}
+
+// Keys returns the declared public sort keys in declaration order.
+func (s *SortSpec) Keys() []string {
+	return append([]string(nil), s.keys...)
+}
+
+// Resolve validates sortBy against the spec and returns the sorting fields keyed by DB field.
+// The default sort is used when sortBy is empty.
+func (s *SortSpec) Resolve(sortBy []string) ([]*SortingField, error) {
+	if len(sortBy) == 0 {
+		sortBy = s.defaultSort
+	}
+	sortingFields, err := getSortingFields(sortBy)
+	if err != nil {
+		return sortingFields, err
+	}
+
+	resolved := make([]*SortingField, 0, len(sortingFields))
+	for _, f := range sortingFields {
+		field, ok := s.fields[f.Key]
+		if !ok {
+			return make([]*SortingField, 0), &SortKeyError{Key: f.Key}
+		}
+		resolved = append(resolved, &SortingField{Key: field, OrderBy: f.OrderBy})
+	}
+	return resolved, nil
+}
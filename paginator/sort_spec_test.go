@@ -0,0 +1,118 @@
+package paginator_test
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/a01k-io/modules/paginator"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm/clause"
+)
+
+type sortableOrder struct {
+	ID        string    `bson:"_id" gorm:"column:order_id" sort:"id"`
+	CreatedAt time.Time `bson:"created_at" gorm:"column:created_on" sort:"created,default=desc"`
+	Amount    int64     `bson:"amount" sort:""`
+	Notes     string    `bson:"internal_notes"`
+}
+
+func TestSortSpecResolve(t *testing.T) {
+	cases := []struct {
+		name    string
+		spec    *paginator.SortSpec
+		sortBy  []string
+		want    []*paginator.SortingField
+		wantErr error
+	}{
+		{
+			name:   "keys mapped to bson fields",
+			spec:   paginator.NewSortSpecFromStruct(sortableOrder{}, "bson"),
+			sortBy: []string{"created:asc", "id:desc"},
+			want: []*paginator.SortingField{
+				{Key: "created_at", OrderBy: paginator.OrderByAsc},
+				{Key: "_id", OrderBy: paginator.OrderByDesc},
+			},
+		},
+		{
+			name:   "keys mapped to gorm columns",
+			spec:   paginator.NewSortSpecFromStruct(&sortableOrder{}, "gorm"),
+			sortBy: []string{"created:asc", "Amount:desc"},
+			want: []*paginator.SortingField{
+				{Key: "created_on", OrderBy: paginator.OrderByAsc},
+				{Key: "amount", OrderBy: paginator.OrderByDesc},
+			},
+		},
+		{
+			name: "default sort when sort_by is empty",
+			spec: paginator.NewSortSpecFromStruct(sortableOrder{}, "bson"),
+			want: []*paginator.SortingField{
+				{Key: "created_at", OrderBy: paginator.OrderByDesc},
+			},
+		},
+		{
+			name:    "undeclared key rejected",
+			spec:    paginator.NewSortSpecFromStruct(sortableOrder{}, "bson"),
+			sortBy:  []string{"Notes:asc"},
+			want:    []*paginator.SortingField{},
+			wantErr: &paginator.SortKeyError{Key: "Notes"},
+		},
+		{
+			name:   "declared manually",
+			spec:   paginator.NewSortSpec().Allow("name", "full_name").Default("name:asc"),
+			sortBy: []string{"name:desc"},
+			want: []*paginator.SortingField{
+				{Key: "full_name", OrderBy: paginator.OrderByDesc},
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := c.spec.Resolve(c.sortBy)
+			assert.Equal(t, c.wantErr, err)
+			assert.Equal(t, c.want, got)
+		})
+	}
+}
+
+func TestNewPaginationQueryParamsFSortSpec(t *testing.T) {
+	filter := paginator.QueryParamFilter{SortSpec: paginator.NewSortSpec().Allow("created", "created_at")}
+
+	r := httptest.NewRequest("GET", "/?page_no=1&page_size=10&sort_by=password:asc", nil)
+	_, err := paginator.NewPaginationQueryParamsF(r, filter)
+	assert.Error(t, err)
+
+	r = httptest.NewRequest("GET", "/?page_no=1&page_size=10&sort_by=created:desc", nil)
+	params, err := paginator.NewPaginationQueryParamsF(r, filter)
+	assert.NoError(t, err)
+	assert.Equal(t, []clause.Expression{
+		clause.OrderBy{Columns: []clause.OrderByColumn{{Column: clause.Column{Name: "created_at"}, Desc: true}}},
+		clause.Limit{Limit: params.PageSizePInt()},
+	}, paginator.BuildPaginationQuery(*params))
+
+	params.SortBy = []string{"password:asc"}
+	_, err = paginator.BuildKeysetPaginationQuery(*params)
+	var keyErr *paginator.SortKeyError
+	assert.True(t, errors.As(err, &keyErr))
+
+	clauses, err := paginator.BuildOffsetPaginationQuery(*params)
+	assert.True(t, errors.As(err, &keyErr))
+	assert.Nil(t, clauses)
+}
+
+func TestSortSpecKeys(t *testing.T) {
+	spec := paginator.NewSortSpec().Allow("name", "full_name").Allow("created", "created_at").Allow("amount", "amount").Allow("name", "name")
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, []string{"name", "created", "amount"}, spec.Keys())
+	}
+	assert.Equal(t, []string{"id", "created", "Amount"}, paginator.NewSortSpecFromStruct(sortableOrder{}, "bson").Keys())
+}
+
+func TestSortSpecDefault(t *testing.T) {
+	spec := paginator.NewSortSpec().Allow("created", "created_at")
+	assert.NotPanics(t, func() { spec.Default("created:desc") })
+	assert.Panics(t, func() { spec.Default("password:asc") }, "undeclared key")
+	assert.Panics(t, func() { spec.Default("created:sideways") }, "invalid direction")
+	assert.Panics(t, func() { spec.Default("created") }, "malformed")
+}
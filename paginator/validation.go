@@ -0,0 +1,180 @@
+package paginator
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gorilla/schema"
+	"github.com/pkg/errors"
+)
+
+const (
+	//CodeInvalid means the value has a wrong format or type
+	CodeInvalid = "invalid"
+	//CodeRequired means the value is missing
+	CodeRequired = "required"
+	//CodeOutOfRange means the value is outside of the allowed range
+	CodeOutOfRange = "out_of_range"
+	//CodeUnknown means the key is not accepted
+	CodeUnknown = "unknown"
+	//CodeTooLong means the value exceeds the max length
+	CodeTooLong = "too_long"
+	//CodeExpired means the value is no longer accepted
+	CodeExpired = "expired"
+
+	// ProblemContentType is the content type of RFC 7807 problem details
+	ProblemContentType = "application/problem+json"
+)
+
+// FieldError describes the validation failure of a single param
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Value   string `json:"value,omitempty"`
+}
+
+// ValidationError lists the validation failures of query or form params
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		messages = append(messages, f.Field+": "+f.Message)
+	}
+	return "invalid query params: " + strings.Join(messages, "; ")
+}
+
+// Add appends a field error
+func (e *ValidationError) Add(field, code, message, value string) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Code: code, Message: message, Value: value})
+}
+
+// Merge appends the field errors err converts to, see AsValidationError.
+// Errors which are not field errors are added under an empty field name.
+func (e *ValidationError) Merge(err error) {
+	if v, ok := AsValidationError(err); ok {
+		e.Fields = append(e.Fields, v.Fields...)
+		return
+	}
+	e.Add("", CodeInvalid, err.Error(), "")
+}
+
+// Err returns e when it has field errors, nil otherwise
+func (e *ValidationError) Err() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	return e
+}
+
+// AsValidationError converts err to ValidationError when it is one of the field errors of this package:
+// ValidationError, SortKeyError, FilterError or CursorError.
+func AsValidationError(err error) (*ValidationError, bool) {
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		return validationErr, true
+	}
+	var sortKeyErr *SortKeyError
+	if errors.As(err, &sortKeyErr) {
+		return &ValidationError{Fields: []FieldError{{
+			Field: sortKeyErr.Field(), Code: CodeUnknown, Message: sortKeyErr.Error(), Value: sortKeyErr.Key,
+		}}}, true
+	}
+	var filterErr *FilterError
+	if errors.As(err, &filterErr) {
+		return &ValidationError{Fields: []FieldError{{
+			Field: filterErr.Field(), Code: CodeInvalid, Message: filterErr.Error(), Value: filterErr.Key,
+		}}}, true
+	}
+	var cursorErr *CursorError
+	if errors.As(err, &cursorErr) {
+		code := CodeInvalid
+		if errors.Is(cursorErr.Reason, ErrCursorExpired) {
+			code = CodeExpired
+		}
+		return &ValidationError{Fields: []FieldError{{
+			Field: "cursor", Code: code, Message: cursorErr.Error(),
+		}}}, true
+	}
+	return nil, false
+}
+
+// newDecodeError maps the errors of the gorilla schema decoder to ValidationError.
+func newDecodeError(err error, values map[string][]string) error {
+	var multiErr schema.MultiError
+	if !errors.As(err, &multiErr) {
+		return errors.New("failed to Decode query param")
+	}
+
+	keys := make([]string, 0, len(multiErr))
+	for k := range multiErr {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	validationErr := &ValidationError{}
+	for _, k := range keys {
+		value := strings.Join(values[k], ",")
+		switch e := multiErr[k].(type) {
+		case schema.ConversionError:
+			validationErr.Add(e.Key, CodeInvalid, fmt.Sprintf("invalid value, expected %v", e.Type), value)
+		case schema.UnknownKeyError:
+			validationErr.Add(e.Key, CodeUnknown, "unknown query param", value)
+		case schema.EmptyFieldError:
+			validationErr.Add(e.Key, CodeRequired, "query param is required", value)
+		default:
+			validationErr.Add(k, CodeInvalid, e.Error(), value)
+		}
+	}
+	return validationErr
+}
+
+// Problem is the RFC 7807 problem details body
+type Problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Errors   []FieldError `json:"errors,omitempty"`
+}
+
+// NewProblem converts err to Problem. Field errors yield 400 Bad Request with the field list,
+// *fiber.Error keeps its code and other errors yield 500 Internal Server Error.
+func NewProblem(err error) Problem {
+	if validationErr, ok := AsValidationError(err); ok {
+		return Problem{
+			Type:   "about:blank",
+			Title:  http.StatusText(http.StatusBadRequest),
+			Status: http.StatusBadRequest,
+			Detail: "one or more params are invalid",
+			Errors: validationErr.Fields,
+		}
+	}
+	status := http.StatusInternalServerError
+	detail := ""
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		status = fiberErr.Code
+		detail = fiberErr.Message
+	}
+	return Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	}
+}
+
+// WriteProblem renders err as application/problem+json response.
+func WriteProblem(c *fiber.Ctx, err error) error {
+	problem := NewProblem(err)
+	problem.Instance = c.OriginalURL()
+	return c.Status(problem.Status).JSON(problem, ProblemContentType)
+}
@@ -0,0 +1,77 @@
+package paginator_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/a01k-io/modules/paginator"
+	"github.com/gofiber/fiber/v2"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewPaginationQueryParamsValidationError(t *testing.T) {
+	cases := []struct {
+		name  string
+		query string
+		want  []paginator.FieldError
+	}{
+		{
+			name:  "conversion error",
+			query: "page_no=abc&page_size=10",
+			want: []paginator.FieldError{
+				{Field: "page_no", Code: paginator.CodeInvalid, Message: "invalid value, expected int64", Value: "abc"},
+			},
+		},
+		{
+			name:  "field errors",
+			query: "page_no=0&page_size=100&sort_by=name&sort_by=age:up",
+			want: []paginator.FieldError{
+				{Field: "page_no", Code: paginator.CodeInvalid, Message: "invalid page_no value: 0", Value: "0"},
+				{Field: "page_size", Code: paginator.CodeOutOfRange, Message: "invalid page_size value it should be in between [1 - 50]", Value: "100"},
+				{Field: "sort_by", Code: paginator.CodeInvalid, Message: "invalid sort_by value: name", Value: "name"},
+				{Field: "sort_by", Code: paginator.CodeInvalid, Message: "invalid sort_by order value: age:up", Value: "age:up"},
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := paginator.NewPaginationQueryParams(httptest.NewRequest("GET", "/?"+c.query, nil))
+			var validationErr *paginator.ValidationError
+			assert.True(t, errors.As(err, &validationErr))
+			assert.Equal(t, c.want, validationErr.Fields)
+		})
+	}
+}
+
+func TestWriteProblem(t *testing.T) {
+	app := fiber.New()
+	app.Get("/orders", paginator.Middleware(paginator.QueryParamFilter{}), func(c *fiber.Ctx) error {
+		return c.SendStatus(http.StatusOK)
+	})
+	app.Get("/fail", func(c *fiber.Ctx) error {
+		return paginator.WriteProblem(c, errors.New("boom"))
+	})
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/orders?page_no=1&page_size=10&cursor=abc", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, paginator.ProblemContentType, resp.Header.Get("Content-Type"))
+
+	var body bytes.Buffer
+	_, _ = body.ReadFrom(resp.Body)
+	assert.JSONEq(t, `{
+		"type": "about:blank",
+		"title": "Bad Request",
+		"status": 400,
+		"detail": "one or more params are invalid",
+		"instance": "/orders?page_no=1&page_size=10&cursor=abc",
+		"errors": [{"field": "cursor", "code": "invalid", "message": "invalid cursor: malformed cursor"}]
+	}`, body.String())
+
+	resp, err = app.Test(httptest.NewRequest("GET", "/fail", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+}